@@ -27,6 +27,8 @@ type CommandContext interface {
 	GetString(key string) (string, error)
 	GetInt(key string) (int, error)
 	GetBool(key string) (bool, error)
+	// GetArgs returns positional arguments left after flags
+	GetArgs() []string
 	Parse(args ...string)
 }
 
@@ -174,6 +176,11 @@ func (c *CommandLineContext) GetString(key string) (string, error) {
 	return fl.stringVal, nil
 }
 
+// GetArgs implements CommandContext.
+func (c *CommandLineContext) GetArgs() []string {
+	return flag.CommandLine.Args()
+}
+
 // Parse implements CommandContext.
 func (c *CommandLineContext) Parse(args ...string) {
 	if c.wasParsed {
@@ -69,7 +69,12 @@ func (as *addSyncCommand) exec(ctx cli.CommandContext) error {
 		return err
 	}
 
-	return filesync.MaybeCreateAndUpdateSyncFile(&conf.Config, src, trg)
+	sd, err := filesync.NewResolver(&conf.Config).FromCommandLine(src, trg)
+	if err != nil {
+		return err
+	}
+
+	return filesync.MaybeCreateAndUpdateSyncFile(&conf.Config, sd.Source, sd.Destination)
 }
 
 func CreateAddSyncCommand(ctx context.Context) *cli.Command {
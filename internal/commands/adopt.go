@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+var ErrNoPathArg error = errors.New("path to adopt was not provided")
+
+const ADOPT_DESC string = "Move existing files or directories into repo and link them back (usage: adopt [flags] PATH...)"
+
+type adoptCommand struct {
+	ctx context.Context
+}
+
+func (a *adoptCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	paths := ctx.GetArgs()
+	if len(paths) < 1 {
+		return ErrNoPathArg
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	if conf.Config.SyncFile == "" {
+		return ErrNotInit
+	}
+
+	for _, p := range paths {
+		_, err := filesync.Adopt(&conf.Config, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CreateAdoptCommand(ctx context.Context) *cli.Command {
+	a := &adoptCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"adopt",
+		ADOPT_DESC,
+		a.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"os"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const DECRYPT_DESC string = "Print decrypted file or write it into output file (usage: decrypt [flags] FILE)"
+
+// DESCRIPTIONS
+const (
+	DECRYPT_OUTPUT_DESC string = "Write decrypted file here, readable only by the owner, instead of printing it"
+)
+
+type decryptCommand struct {
+	ctx context.Context
+}
+
+func (d *decryptCommand) exec(ctx cli.CommandContext) error {
+	output, err := ctx.GetString(OUTPUT_FLAG)
+	if err != nil {
+		return err
+	}
+
+	files := ctx.GetArgs()
+	if len(files) != 1 {
+		return ErrNoFileArg
+	}
+
+	id, err := loadIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	plain, err := filesync.DecryptFile(id, files[0])
+	if err != nil {
+		return err
+	}
+	if output == SRC_TRG_DEFAULT_VALUE {
+		_, err = os.Stdout.Write(plain)
+		return err
+	}
+	return os.WriteFile(output, plain, 0600)
+}
+
+func CreateDecryptCommand(ctx context.Context) *cli.Command {
+	d := &decryptCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"decrypt",
+		DECRYPT_DESC,
+		d.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(OUTPUT_FLAG, DECRYPT_OUTPUT_DESC, cli.StringFlag, SRC_TRG_DEFAULT_VALUE, "o"),
+	)
+}
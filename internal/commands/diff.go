@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const DIFF_DESC string = "Show differences between repo and copied destinations (usage: diff [flags] [TARGET...])"
+
+type diffCommand struct {
+	ctx context.Context
+}
+
+func (d *diffCommand) exec(ctx cli.CommandContext) error {
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.CopiedEntries(&conf.Config, ctx.GetArgs())
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, es := range entries {
+		if es.InSync() || es.State == filesync.StateMissing || es.State == filesync.StateSourceMissing {
+			continue
+		}
+		diff, err := filesync.DiffEntry(es)
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			changed++
+			fmt.Print(diff)
+		}
+	}
+
+	if changed > 0 {
+		return fmt.Errorf("(count = %d) %w", changed, ErrOutOfSync)
+	}
+	return nil
+}
+
+func CreateDiffCommand(ctx context.Context) *cli.Command {
+	d := &diffCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"diff",
+		DIFF_DESC,
+		d.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+	)
+}
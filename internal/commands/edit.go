@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const EDIT_DESC string = "Decrypt file into private temporary file, open it in $EDITOR and encrypt changes back (usage: edit [flags] FILE)"
+
+// DEFAULTS
+const (
+	EDITOR_DEFAULT string = "vi"
+)
+
+type editCommand struct {
+	ctx context.Context
+}
+
+func (e *editCommand) exec(ctx cli.CommandContext) error {
+	files := ctx.GetArgs()
+	if len(files) != 1 {
+		return ErrNoFileArg
+	}
+	file := files[0]
+
+	id, err := loadIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	// missing file is created so new secrets never touch the disk unencrypted
+	plain := []byte{}
+	if _, err := os.Stat(file); err == nil {
+		plain, err = filesync.DecryptFile(id, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ftuck-edit-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpFile := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(file), ".enc"))
+	err = os.WriteFile(tmpFile, plain, 0600)
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C belongs to the editor, it must not kill it and lose the changes
+	err = runEditor(context.WithoutCancel(e.ctx), tmpFile)
+	if err != nil {
+		return err
+	}
+
+	edited, err := os.ReadFile(tmpFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, plain) {
+		slog.Info("file not changed", "file", file)
+		return nil
+	}
+	return filesync.EncryptFile(id, edited, file)
+}
+
+// runEditor opens file in $VISUAL or $EDITOR, editor command may contain arguments
+func runEditor(ctx context.Context, file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = EDITOR_DEFAULT
+	}
+	args := strings.Fields(editor)
+
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func CreateEditCommand(ctx context.Context) *cli.Command {
+	e := &editCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"edit",
+		EDIT_DESC,
+		e.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
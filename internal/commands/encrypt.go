@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+var (
+	ErrNoFileArg        error = errors.New("file was not provided")
+	ErrAlreadyEncrypted error = errors.New("file is already encrypted")
+	ErrSingleFileOutput error = errors.New("output can be given only for a single file")
+)
+
+const ENCRYPT_DESC string = "Encrypt files in place or into output file (usage: encrypt [flags] FILE...)"
+
+// FLAGS
+const (
+	OUTPUT_FLAG string = "output"
+)
+
+// DESCRIPTIONS
+const (
+	ENCRYPT_OUTPUT_DESC string = "Write encrypted file here instead of replacing the input"
+)
+
+// loadIdentity reads identity file configured in given configuration
+func loadIdentity(ctx cli.CommandContext) (*filesync.Identity, error) {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return filesync.LoadIdentity(conf.Config.GetIdentityFile())
+}
+
+type encryptCommand struct {
+	ctx context.Context
+}
+
+func (e *encryptCommand) exec(ctx cli.CommandContext) error {
+	output, err := ctx.GetString(OUTPUT_FLAG)
+	if err != nil {
+		return err
+	}
+
+	files := ctx.GetArgs()
+	if len(files) < 1 {
+		return ErrNoFileArg
+	}
+	if output != SRC_TRG_DEFAULT_VALUE && len(files) > 1 {
+		return ErrSingleFileOutput
+	}
+
+	id, err := loadIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		d, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if filesync.IsEncrypted(d) {
+			return fmt.Errorf("(file = %s) %w", f, ErrAlreadyEncrypted)
+		}
+		out := f
+		if output != SRC_TRG_DEFAULT_VALUE {
+			out = output
+		}
+		err = filesync.EncryptFile(id, d, out)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CreateEncryptCommand(ctx context.Context) *cli.Command {
+	e := &encryptCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"encrypt",
+		ENCRYPT_DESC,
+		e.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(OUTPUT_FLAG, ENCRYPT_OUTPUT_DESC, cli.StringFlag, SRC_TRG_DEFAULT_VALUE, "o"),
+	)
+}
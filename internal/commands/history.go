@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const HISTORY_DESC string = "List recorded sync, adopt and remove runs, or actions of one run (usage: history [flags] [RUN-ID])"
+
+type historyCommand struct {
+	ctx context.Context
+}
+
+func (h *historyCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	runs, err := filesync.ListRuns(&conf.Config)
+	if err != nil {
+		return err
+	}
+
+	args := ctx.GetArgs()
+	if len(args) == 0 {
+		return printRuns(runs)
+	}
+	for _, run := range runs {
+		if run.ID == args[0] {
+			return printRunActions(run)
+		}
+	}
+	return fmt.Errorf("(run = %s) %w", args[0], filesync.ErrRunNotFound)
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func printRuns(runs []filesync.Run) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCOMMAND\tSTARTED\tCOMMIT\tACTIONS\tUNDONE")
+	for _, run := range runs {
+		undone := ""
+		if run.Undone != nil {
+			undone = run.Undone.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", run.ID, run.Command, run.Started.Format(time.DateTime), shortCommit(run.Commit), len(run.Actions), undone)
+	}
+	return w.Flush()
+}
+
+func printRunActions(run filesync.Run) error {
+	fmt.Printf("run %s: %s started %s, finished %s\n", run.ID, run.Command, run.Started.Format(time.DateTime), run.Finished.Format(time.DateTime))
+	if run.Commit != "" {
+		fmt.Printf("commit %s\n", run.Commit)
+	}
+	if run.Undone != nil {
+		fmt.Printf("undone %s\n", run.Undone.Format(time.DateTime))
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tDESTINATION\tSOURCE\tREASON")
+	for _, a := range run.Actions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Kind, a.Destination, a.Source, a.Reason)
+	}
+	return w.Flush()
+}
+
+func CreateHistoryCommand(ctx context.Context) *cli.Command {
+	h := &historyCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"history",
+		HISTORY_DESC,
+		h.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
@@ -11,20 +11,23 @@ import (
 
 // FLAGS
 const (
-	CONF_FLAG string = "conf"
-	WD_FLAG   string = "workdir"
+	CONF_FLAG    string = "conf"
+	WD_FLAG      string = "workdir"
+	PROFILE_FLAG string = "profile"
 )
 
 // DEFAULTS
 var (
-	CONF_DEFAULT string = path.Join(os.Getenv("HOME"), ".ftuck.yaml")
-	WD_DEFAULt   string = "not provided"
+	CONF_DEFAULT    string = path.Join(os.Getenv("HOME"), ".ftuck.yaml")
+	WD_DEFAULt      string = "not provided"
+	PROFILE_DEFAULT string = ""
 )
 
 // DESCRITIOPN
 var (
-	CONF_DESC string = "Specify configuration path. (DEFAULT=" + CONF_DEFAULT + ")"
-	WD_DESC   string = "Use different working directory than current."
+	CONF_DESC    string = "Specify configuration path. (DEFAULT=" + CONF_DEFAULT + ")"
+	WD_DESC      string = "Use different working directory than current."
+	PROFILE_DESC string = "Comma separated list of profiles to sync. (DEFAULT=profiles from configuration or sync file settings)"
 )
 
 type initCommand struct {
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const KEYGEN_DESC string = "Create identity used to encrypt and decrypt secrets at configured identity file path"
+
+type keygenCommand struct {
+	ctx context.Context
+}
+
+func (k *keygenCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	id, err := filesync.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	p := conf.Config.GetIdentityFile()
+	err = id.Save(p)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "identity written to %s, keep a copy of it outside of the repo\n", p)
+	fmt.Println(id.PublicKey())
+	return nil
+}
+
+func CreateKeygenCommand(ctx context.Context) *cli.Command {
+	k := &keygenCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"keygen",
+		KEYGEN_DESC,
+		k.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/filesync"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFormat error = errors.New("unknown output format")
+
+const LIST_DESC string = "List sync entries with resolved paths"
+
+// FLAGS
+const (
+	FORMAT_FLAG string = "format"
+)
+
+// DEFAULTS
+const (
+	FORMAT_TABLE string = "table"
+	FORMAT_JSON  string = "json"
+	FORMAT_YAML  string = "yaml"
+)
+
+// DESCRIPTIONS
+const (
+	FORMAT_DESC string = "Output format: table, json or yaml"
+)
+
+type listCommand struct {
+	ctx context.Context
+}
+
+func (l *listCommand) exec(ctx cli.CommandContext) error {
+	format, err := ctx.GetString(FORMAT_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.Resolve(&conf.Config)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FORMAT_TABLE:
+		return printEntriesTable(entries)
+	case FORMAT_JSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case FORMAT_YAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		defer enc.Close()
+		return enc.Encode(entries)
+	default:
+		return fmt.Errorf("(format = %s) %w", format, ErrUnknownFormat)
+	}
+}
+
+func printEntriesTable(entries []filesync.ResolvedEntry) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DESTINATION\tSOURCE\tSKIPPED")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Destination, e.Source, e.SkipReason)
+	}
+	return w.Flush()
+}
+
+func CreateListCommand(ctx context.Context) *cli.Command {
+	l := &listCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"list",
+		LIST_DESC,
+		l.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+		cli.RegisterFlag(FORMAT_FLAG, FORMAT_DESC, cli.StringFlag, FORMAT_TABLE, "f"),
+	)
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const MIGRATE_DESC string = "Rewrite sync file to current schema version keeping comments"
+
+// DESCRIPTIONS
+const (
+	MIGRATE_DRY_RUN_DESC string = "Print migrated sync file instead of saving it"
+)
+
+type migrateCommand struct {
+	ctx context.Context
+}
+
+func (m *migrateCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := ctx.GetBool(DRY_RUN_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	if conf.Config.SyncFile == "" {
+		return ErrNotInit
+	}
+
+	d, err := os.ReadFile(conf.Config.SyncFile)
+	if err != nil {
+		return err
+	}
+
+	migrated, changed, err := filesync.MigrateSchema(d)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Print(string(migrated))
+		return nil
+	}
+
+	if !changed {
+		slog.Info("sync file already in current version", "path", conf.Config.SyncFile, "version", filesync.SCHEMA_VERSION)
+		return nil
+	}
+
+	slog.Info("migrating sync file", "path", conf.Config.SyncFile, "version", filesync.SCHEMA_VERSION)
+	return filesync.WriteSyncFile(conf.Config.SyncFile, migrated)
+}
+
+func CreateMigrateCommand(ctx context.Context) *cli.Command {
+	m := &migrateCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"migrate",
+		MIGRATE_DESC,
+		m.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(DRY_RUN_FLAG, MIGRATE_DRY_RUN_DESC, cli.BoolFlag, false, "n"),
+	)
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+)
+
+const PRUNE_COMMAND_DESC string = "Remove links created by ftuck for entries which are no longer in sync file"
+
+type pruneCommand struct {
+	ctx context.Context
+}
+
+func (pr *pruneCommand) exec(ctx cli.CommandContext) error {
+	dryRun, err := ctx.GetBool(DRY_RUN_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.Prune(&conf.Config)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return printPlan(p)
+	}
+	return p.ExecuteContext(pr.ctx)
+}
+
+func CreatePruneCommand(ctx context.Context) *cli.Command {
+	pr := &pruneCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"prune",
+		PRUNE_COMMAND_DESC,
+		pr.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+		cli.RegisterFlag(DRY_RUN_FLAG, DRY_RUN_DESC, cli.BoolFlag, false, "n"),
+	)
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+)
+
+const PULL_DESC string = "Copy changes made in copied destinations back into repo (usage: pull [flags] [TARGET...])"
+
+// FLAGS
+const (
+	FORCE_FLAG string = "force"
+)
+
+// DESCRIPTIONS
+const (
+	PULL_FORCE_DESC string = "Pull even when repo file changed as well since last sync"
+)
+
+type pullCommand struct {
+	ctx context.Context
+}
+
+func (pl *pullCommand) exec(ctx cli.CommandContext) error {
+	force, err := ctx.GetBool(FORCE_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Pull(&conf.Config, ctx.GetArgs(), force)
+	return err
+}
+
+func CreatePullCommand(ctx context.Context) *cli.Command {
+	pl := &pullCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"pull",
+		PULL_DESC,
+		pl.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+		cli.RegisterFlag(FORCE_FLAG, PULL_FORCE_DESC, cli.BoolFlag, false, "f"),
+	)
+}
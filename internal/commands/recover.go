@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const RECOVER_DESC string = "Roll back sync which was interrupted before it finished"
+
+type recoverCommand struct {
+	ctx context.Context
+}
+
+func (r *recoverCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	recovered, err := filesync.RecoverSync(&conf.Config)
+	if err != nil {
+		return err
+	}
+	if !recovered {
+		fmt.Println("nothing to recover")
+		return nil
+	}
+	fmt.Println("interrupted sync rolled back")
+	return nil
+}
+
+func CreateRecoverCommand(ctx context.Context) *cli.Command {
+	r := &recoverCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"recover",
+		RECOVER_DESC,
+		r.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const REMOVE_DESC string = "Remove file sync from current config"
+
+// FLAGS
+const (
+	UNLINK_FLAG       string = "unlink"
+	RESTORE_FILE_FLAG string = "restore"
+)
+
+// DESCRIPTIONS
+const (
+	REMOVE_TARGET_DESC string = "Remove entries with this target"
+	REMOVE_SOURCE_DESC string = "Remove entries with this source"
+	UNLINK_DESC        string = "Also remove what sync put at target: links into repo, hardlinks and unchanged copies"
+	RESTORE_FILE_DESC  string = "Put a copy of the repo file in place of removed links (implies -unlink)"
+)
+
+type removeCommand struct {
+	ctx context.Context
+}
+
+func (r *removeCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	trg, err := ctx.GetString(TARGET_FLAG)
+	if err != nil {
+		return err
+	}
+	if trg == SRC_TRG_DEFAULT_VALUE {
+		trg = ""
+	}
+
+	src, err := ctx.GetString(SOURCE_FLAG)
+	if err != nil {
+		return err
+	}
+	if src == SRC_TRG_DEFAULT_VALUE {
+		src = ""
+	}
+
+	if src == "" && trg == "" {
+		return fmt.Errorf("(flag = %s or %s) %w", TARGET_FLAG, SOURCE_FLAG, ErrObligatoryFlag)
+	}
+
+	opts := filesync.RemoveOptions{}
+	opts.Unlink, err = ctx.GetBool(UNLINK_FLAG)
+	if err != nil {
+		return err
+	}
+	opts.Restore, err = ctx.GetBool(RESTORE_FILE_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	if conf.Config.SyncFile == "" {
+		return ErrNotInit
+	}
+
+	_, err = filesync.RemoveFromSyncFile(&conf.Config, src, trg, opts)
+	return err
+}
+
+func CreateRemoveCommand(ctx context.Context) *cli.Command {
+	r := &removeCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc("remove", REMOVE_DESC, r.exec,
+		cli.RegisterFlag(TARGET_FLAG, REMOVE_TARGET_DESC, cli.StringFlag, SRC_TRG_DEFAULT_VALUE, "t"),
+		cli.RegisterFlag(SOURCE_FLAG, REMOVE_SOURCE_DESC, cli.StringFlag, SRC_TRG_DEFAULT_VALUE, "s"),
+		cli.RegisterFlag(UNLINK_FLAG, UNLINK_DESC, cli.BoolFlag, false, "u"),
+		cli.RegisterFlag(RESTORE_FILE_FLAG, RESTORE_FILE_DESC, cli.BoolFlag, false, "r"),
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const RESTORE_DESC string = "Put files moved away by sync --backup back in place"
+
+// FLAGS
+const (
+	TIMESTAMP_FLAG string = "timestamp"
+	LIST_FLAG      string = "list"
+)
+
+// DEFAULTS
+const (
+	TIMESTAMP_DEFAULT string = "latest"
+)
+
+// DESCRIPTIONS
+const (
+	TIMESTAMP_DESC    string = "Backup set to restore (format " + filesync.BACKUP_TIME_FORMAT + "). Latest one by default"
+	LIST_BACKUPS_DESC string = "Only list available backup sets"
+)
+
+type restoreCommand struct {
+	ctx context.Context
+}
+
+func (r *restoreCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := ctx.GetString(TIMESTAMP_FLAG)
+	if err != nil {
+		return err
+	}
+	if timestamp == TIMESTAMP_DEFAULT {
+		timestamp = ""
+	}
+
+	list, err := ctx.GetBool(LIST_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+	backupDir := conf.Config.GetBackupDir()
+
+	if list {
+		sets, err := filesync.ListBackups(backupDir)
+		if err != nil {
+			return err
+		}
+		for _, s := range sets {
+			fmt.Println(s)
+		}
+		return nil
+	}
+
+	return filesync.RestoreBackup(backupDir, timestamp)
+}
+
+func CreateRestoreCommand(ctx context.Context) *cli.Command {
+	r := &restoreCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"restore",
+		RESTORE_DESC,
+		r.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(TIMESTAMP_FLAG, TIMESTAMP_DESC, cli.StringFlag, TIMESTAMP_DEFAULT, "ts"),
+		cli.RegisterFlag(LIST_FLAG, LIST_BACKUPS_DESC, cli.BoolFlag, false, "l"),
+	)
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+// loadSchema opens configuration pointed by CONF_FLAG, reads sync definitions from configured sync file
+// and selects profiles given with PROFILE_FLAG or default ones from configuration
+func loadSchema(ctx cli.CommandContext) (*config.ConfigFile, *filesync.Schema, error) {
+	// get flag values
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if conf.Config.SyncFile == "" {
+		return nil, nil, ErrNotInit
+	}
+
+	// read sync definitions
+	d, err := filesync.ReadOrCreate(conf.Config.SyncFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w : run init again", err)
+	}
+
+	s, err := filesync.ReadSchema(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profiles, err := ctx.GetString(PROFILE_FLAG)
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := filesync.ParseProfileList(profiles)
+	if len(selected) == 0 {
+		selected = conf.Config.Profiles
+	}
+	if len(selected) == 0 {
+		selected = s.Settings.DefaultProfiles
+	}
+	err = s.SelectProfiles(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conf, s, nil
+}
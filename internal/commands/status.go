@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+var ErrOutOfSync error = errors.New("some entries are out of sync")
+
+const STATUS_DESC string = "Show state of every entry in sync file without changing anything"
+
+type statusCommand struct {
+	ctx context.Context
+}
+
+func (st *statusCommand) exec(ctx cli.CommandContext) error {
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := s.Status(&conf.Config)
+	if err != nil {
+		return err
+	}
+
+	outOfSync := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATE\tDESTINATION\tSOURCE")
+	for _, es := range statuses {
+		if !es.InSync() {
+			outOfSync++
+		}
+		src := es.Source
+		switch {
+		case es.State == filesync.StateElsewhere:
+			src = fmt.Sprintf("%s (links to %s)", es.Source, es.LinkTarget)
+		case es.Reason != "":
+			src = fmt.Sprintf("%s (%s)", es.Source, es.Reason)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", es.State, es.Destination, src)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if outOfSync > 0 {
+		return fmt.Errorf("(count = %d) %w", outOfSync, ErrOutOfSync)
+	}
+	return nil
+}
+
+func CreateStatusCommand(ctx context.Context) *cli.Command {
+	st := &statusCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"status",
+		STATUS_DESC,
+		st.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+	)
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+var ErrNoPackageArg error = errors.New("package name was not provided")
+
+const STOW_DESC string = "Declare repo subdirectories as packages and mirror them into target (usage: stow [flags] PACKAGE...)"
+
+// DESCRIPTIONS
+const (
+	STOW_TARGET_DESC string = "Directory packages are mirrored into. (DEFAULT=home directory)"
+)
+
+type stowCommand struct {
+	ctx context.Context
+}
+
+func (st *stowCommand) exec(ctx cli.CommandContext) error {
+	names := ctx.GetArgs()
+	if len(names) < 1 {
+		return ErrNoPackageArg
+	}
+
+	trg, err := ctx.GetString(TARGET_FLAG)
+	if err != nil {
+		return err
+	}
+	if trg == SRC_TRG_DEFAULT_VALUE {
+		trg = ""
+	}
+
+	dryRun, err := ctx.GetBool(DRY_RUN_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	if trg != "" {
+		trg, err = filesync.NewResolver(&conf.Config).PortablePath(trg)
+		if err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	for _, name := range names {
+		if s.AddPackage(filesync.Package{Name: name, Target: trg}) {
+			changed = true
+		}
+	}
+	if changed && !dryRun {
+		err := s.WriteToFile(conf.Config.SyncFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	pkgs, err := s.SelectPackages(names)
+	if err != nil {
+		return err
+	}
+
+	p, err := pkgs.Plan(&conf.Config, filesync.PlanOptions{})
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return printPlan(p)
+	}
+	return p.ExecuteContext(st.ctx)
+}
+
+func CreateStowCommand(ctx context.Context) *cli.Command {
+	st := &stowCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"stow",
+		STOW_DESC,
+		st.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+		cli.RegisterFlag(TARGET_FLAG, STOW_TARGET_DESC, cli.StringFlag, SRC_TRG_DEFAULT_VALUE, "t"),
+		cli.RegisterFlag(DRY_RUN_FLAG, DRY_RUN_DESC, cli.BoolFlag, false, "n"),
+	)
+}
@@ -2,44 +2,153 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
 
 	"github.com/mustafmst/ftuck/internal/cli"
 	"github.com/mustafmst/ftuck/internal/config"
 	"github.com/mustafmst/ftuck/internal/filesync"
 )
 
+// FLAGS
+const (
+	DRY_RUN_FLAG    string = "dry-run"
+	BACKUP_FLAG     string = "backup"
+	UNFOLD_FLAG     string = "unfold"
+	KEEP_GOING_FLAG string = "keep-going"
+	PRUNE_FLAG      string = "prune"
+)
+
+// DESCRIPTIONS
+const (
+	DRY_RUN_DESC    string = "Only print planned changes without touching the filesystem"
+	BACKUP_DESC     string = "Move existing files to timestamped backup dir and replace them with links"
+	UNFOLD_DESC     string = "Replace directory links into repo with real directories when mirrored entries need them"
+	KEEP_GOING_DESC string = "Sync remaining entries when some fail and print summary of failures"
+	PRUNE_DESC      string = "Also remove links created by ftuck for entries no longer in sync file"
+)
+
 type syncAllCommand struct {
 	ctx context.Context
 }
 
 func (sa *syncAllCommand) exec(ctx cli.CommandContext) error {
-	// get flag values
-	confPath, err := ctx.GetString(CONF_FLAG)
+	dryRun, err := ctx.GetBool(DRY_RUN_FLAG)
 	if err != nil {
 		return err
 	}
 
-	conf, err := config.OpenConfigFile(confPath)
+	backup, err := ctx.GetBool(BACKUP_FLAG)
 	if err != nil {
 		return err
 	}
 
-	if conf.Config.SyncFile == "" {
-		return ErrNotInit
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
 	}
 
-	// read sync definitions
-	d, err := filesync.ReadOrCreate(conf.Config.SyncFile)
+	opts := filesync.PlanOptions{}
+	opts.Unfold, err = ctx.GetBool(UNFOLD_FLAG)
+	if err != nil {
+		return err
+	}
+	opts.KeepGoing, err = ctx.GetBool(KEEP_GOING_FLAG)
 	if err != nil {
-		return fmt.Errorf("%w : run init again", err)
+		return err
+	}
+	opts.Prune, err = ctx.GetBool(PRUNE_FLAG)
+	if err != nil {
+		return err
+	}
+	if backup {
+		opts.BackupDir = conf.Config.GetBackupDir()
 	}
 
-	s, err := filesync.ReadSchema(d)
+	// with keep-going invalid entries fail without being synced and the rest is synced
+	issues, err := syncFileIssues(conf)
 	if err != nil {
 		return err
 	}
-	return s.SyncAllEntries(&conf.Config)
+	if len(issues) > 0 && !opts.KeepGoing {
+		printIssues(conf, issues)
+		return fmt.Errorf("(issues = %d) %w", len(issues), ErrInvalidSchema)
+	}
+	opts.Issues = issues
+
+	p, err := s.Plan(&conf.Config, opts)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		printIssues(conf, issues)
+		return printPlan(p)
+	}
+	if !opts.KeepGoing {
+		return p.ExecuteContext(sa.ctx)
+	}
+	failures, err := p.ExecuteKeepGoing(sa.ctx)
+	failures = append(issueFailures(conf, issues), failures...)
+	if len(failures) > 0 {
+		printFailures(failures)
+	}
+	if len(issues) > 0 {
+		err = errors.Join(err, fmt.Errorf("(issues = %d) %w", len(issues), ErrInvalidSchema))
+	}
+	return err
+}
+
+// issueFailures turns issues of whole sync file into failures printed in keep-going summary,
+// issues of entries fail their entries when plan is executed
+func issueFailures(conf *config.ConfigFile, issues []filesync.Issue) []filesync.Failure {
+	failures := []filesync.Failure{}
+	for _, i := range issues {
+		if i.InEntry() {
+			continue
+		}
+		failures = append(failures, filesync.Failure{
+			Kind: filesync.FAILURE_INVALID,
+			Err:  fmt.Errorf("%s:%s", conf.Config.SyncFile, i),
+		})
+	}
+	return failures
+}
+
+// printFailures prints failed entries grouped by kind of failure
+func printFailures(failures []filesync.Failure) {
+	byKind := map[filesync.FailureKind][]filesync.Failure{}
+	for _, f := range failures {
+		byKind[f.Kind] = append(byKind[f.Kind], f)
+	}
+	kinds := slices.Sorted(maps.Keys(byKind))
+
+	fmt.Printf("%d entries failed to sync\n", len(failures))
+	for _, k := range kinds {
+		fmt.Printf("\n%s (%d):\n", k, len(byKind[k]))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range byKind[k] {
+			dest := f.Destination
+			if dest == "" {
+				dest = "(sync file)"
+			}
+			fmt.Fprintf(w, "  %s\t%v\n", dest, f.Err)
+		}
+		w.Flush()
+	}
+}
+
+func printPlan(p filesync.Plan) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tDESTINATION\tSOURCE\tREASON")
+	for _, a := range p.Actions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Kind, a.Destination, a.Source, a.Reason)
+	}
+	return w.Flush()
 }
 
 func CreateSyncAllCommand(ctx context.Context) *cli.Command {
@@ -51,5 +160,11 @@ func CreateSyncAllCommand(ctx context.Context) *cli.Command {
 		"Sync files with current configuration",
 		sa.exec,
 		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+		cli.RegisterFlag(DRY_RUN_FLAG, DRY_RUN_DESC, cli.BoolFlag, false, "n"),
+		cli.RegisterFlag(BACKUP_FLAG, BACKUP_DESC, cli.BoolFlag, false, "b"),
+		cli.RegisterFlag(UNFOLD_FLAG, UNFOLD_DESC, cli.BoolFlag, false, "u"),
+		cli.RegisterFlag(KEEP_GOING_FLAG, KEEP_GOING_DESC, cli.BoolFlag, false, "k"),
+		cli.RegisterFlag(PRUNE_FLAG, PRUNE_DESC, cli.BoolFlag, false),
 	)
 }
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+const UNDO_DESC string = "Revert changes of recorded run, the newest one by default (usage: undo [flags] [RUN-ID])"
+
+// DESCRIPTIONS
+const (
+	UNDO_FORCE_DESC string = "Undo even when sync file changed since the run, paths changed since are left as they are"
+)
+
+type undoCommand struct {
+	ctx context.Context
+}
+
+func (u *undoCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	force, err := ctx.GetBool(FORCE_FLAG)
+	if err != nil {
+		return err
+	}
+
+	id := ""
+	if args := ctx.GetArgs(); len(args) > 0 {
+		id = args[0]
+	}
+
+	run, err := filesync.UndoRun(&conf.Config, id, force)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("undone %s run %s\n", run.Command, run.ID)
+	return nil
+}
+
+func CreateUndoCommand(ctx context.Context) *cli.Command {
+	u := &undoCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"undo",
+		UNDO_DESC,
+		u.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(FORCE_FLAG, UNDO_FORCE_DESC, cli.BoolFlag, false, "f"),
+	)
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+	"github.com/mustafmst/ftuck/internal/config"
+	"github.com/mustafmst/ftuck/internal/filesync"
+)
+
+var ErrInvalidSchema error = errors.New("sync file is not valid")
+
+const VALIDATE_DESC string = "Check sync file for mistakes"
+
+// validateSyncFile prints every issue found in configured sync file to stderr
+func validateSyncFile(conf *config.ConfigFile) error {
+	issues, err := syncFileIssues(conf)
+	if err != nil {
+		return err
+	}
+	printIssues(conf, issues)
+	if len(issues) > 0 {
+		return fmt.Errorf("(issues = %d) %w", len(issues), ErrInvalidSchema)
+	}
+	return nil
+}
+
+// syncFileIssues validates configured sync file with variables of this machine
+func syncFileIssues(conf *config.ConfigFile) ([]filesync.Issue, error) {
+	d, err := os.ReadFile(conf.Config.SyncFile)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := filesync.MachineVars(&conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	r := filesync.NewResolver(&conf.Config)
+	r.Vars = filesync.VarsMap(vars)
+
+	issues, err := filesync.ValidateSchema(d, r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", conf.Config.SyncFile, err)
+	}
+	return issues, nil
+}
+
+func printIssues(conf *config.ConfigFile, issues []filesync.Issue) {
+	for _, i := range issues {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", conf.Config.SyncFile, i)
+	}
+}
+
+type validateCommand struct {
+	ctx context.Context
+}
+
+func (v *validateCommand) exec(ctx cli.CommandContext) error {
+	confPath, err := ctx.GetString(CONF_FLAG)
+	if err != nil {
+		return err
+	}
+
+	conf, err := config.OpenConfigFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	if conf.Config.SyncFile == "" {
+		return ErrNotInit
+	}
+
+	return validateSyncFile(conf)
+}
+
+func CreateValidateCommand(ctx context.Context) *cli.Command {
+	v := &validateCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"validate",
+		VALIDATE_DESC,
+		v.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+	)
+}
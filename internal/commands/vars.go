@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mustafmst/ftuck/internal/cli"
+)
+
+const VARS_DESC string = "Show effective variables and where each of them was set"
+
+type varsCommand struct {
+	ctx context.Context
+}
+
+func (v *varsCommand) exec(ctx cli.CommandContext) error {
+	conf, s, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	vars, err := s.Variables(&conf.Config)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE\tORIGIN")
+	for _, v := range vars {
+		origin := v.Origin
+		if v.Path != "" {
+			origin = fmt.Sprintf("%s (%s)", v.Origin, v.Path)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Name, v.Value, origin)
+	}
+	return w.Flush()
+}
+
+func CreateVarsCommand(ctx context.Context) *cli.Command {
+	v := &varsCommand{
+		ctx: ctx,
+	}
+	return cli.NewCommandWithFunc(
+		"vars",
+		VARS_DESC,
+		v.exec,
+		cli.RegisterFlag(CONF_FLAG, CONF_DESC, cli.StringFlag, CONF_DEFAULT, "c"),
+		cli.RegisterFlag(PROFILE_FLAG, PROFILE_DESC, cli.StringFlag, PROFILE_DEFAULT, "p"),
+	)
+}
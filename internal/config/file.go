@@ -3,18 +3,82 @@ package config
 import (
 	"log/slog"
 	"os"
+	"path"
 
 	"gopkg.in/yaml.v3"
 )
 
+// CONFIG_FILE_PERM is used when configuration is created, it points to identity
+// and holds machine variables so it is readable only by the owner
+const CONFIG_FILE_PERM os.FileMode = 0600
+
 type Config struct {
-	SyncFile string `yaml:"syncfile"`
+	SyncFile  string `yaml:"syncfile"`
+	BackupDir string `yaml:"backupdir,omitempty"`
+	// Profiles activated when none are given on command line
+	Profiles []string `yaml:"profiles,omitempty"`
+	StateDir string   `yaml:"statedir,omitempty"`
+	// Vars override variables from sync file on this machine
+	Vars map[string]string `yaml:"vars,omitempty"`
+	// VarsFile is a local yaml file with variables overriding the ones above,
+	// meant to stay out of the repo
+	VarsFile string `yaml:"varsfile,omitempty"`
+	// IdentityFile holds private key decrypting encrypted entries, never commit it
+	IdentityFile string `yaml:"identityfile,omitempty"`
 }
 
 func (c *Config) GetSyncFilePath() string {
 	return c.SyncFile
 }
 
+// GetBackupDir returns directory where files replaced by sync are moved.
+// Defaults to $XDG_DATA_HOME/ftuck/backups.
+func (c *Config) GetBackupDir() string {
+	if c.BackupDir != "" {
+		return c.BackupDir
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = path.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	return path.Join(dataHome, "ftuck", "backups")
+}
+
+// GetStateDir returns directory where ftuck keeps what it knows about this machine.
+// Defaults to $XDG_STATE_HOME/ftuck.
+func (c *Config) GetStateDir() string {
+	if c.StateDir != "" {
+		return c.StateDir
+	}
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		stateHome = path.Join(os.Getenv("HOME"), ".local", "state")
+	}
+	return path.Join(stateHome, "ftuck")
+}
+
+// GetVars returns variables set for this machine
+func (c *Config) GetVars() map[string]string {
+	return c.Vars
+}
+
+func (c *Config) GetVarsFile() string {
+	return c.VarsFile
+}
+
+// GetIdentityFile returns path of key used to encrypt and decrypt secrets.
+// Defaults to $XDG_CONFIG_HOME/ftuck/identity.
+func (c *Config) GetIdentityFile() string {
+	if c.IdentityFile != "" {
+		return c.IdentityFile
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = path.Join(os.Getenv("HOME"), ".config")
+	}
+	return path.Join(configHome, "ftuck", "identity")
+}
+
 type ConfigFile struct {
 	path   string
 	Config Config
@@ -26,7 +90,7 @@ func (c *ConfigFile) Save() error {
 		return err
 	}
 
-	err = os.WriteFile(c.path, d, 0644)
+	err = os.WriteFile(c.path, d, CONFIG_FILE_PERM)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,112 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrAlreadyLinked = errors.New("path is already a symlink")
+	ErrInsideRepo    = errors.New("path is already inside repo")
+	ErrRepoPathTaken = errors.New("path inside repo already exists")
+)
+
+// adoptedName returns path relative to repo under which adopted file is stored.
+// Files from home directory keep their layout, others only keep base name.
+func adoptedName(target string, home string) string {
+	if home != "" && isWithin(home, target) && target != home {
+		rel, _ := filepath.Rel(home, target)
+		return rel
+	}
+	return filepath.Base(target)
+}
+
+// Adopt moves file or directory into repo, records new sync definition
+// with source relative to repo and links original location back to it.
+func Adopt(conf syncFileGetter, target string) (SyncDefinition, error) {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return SyncDefinition{}, err
+	}
+
+	fi, err := os.Lstat(target)
+	if err != nil {
+		return SyncDefinition{}, err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return SyncDefinition{}, fmt.Errorf("(path = %s) %w", target, ErrAlreadyLinked)
+	}
+
+	r := NewResolver(conf)
+	repo := r.RepoDir
+	if isWithin(repo, target) {
+		return SyncDefinition{}, fmt.Errorf("(path = %s) %w", target, ErrInsideRepo)
+	}
+
+	sd := SyncDefinition{
+		Source:      adoptedName(target, r.HomeDir),
+		Destination: portablePath(target, r.HomeDir),
+	}
+	repoPath := filepath.Join(repo, sd.Source)
+	if _, err := os.Lstat(repoPath); err == nil {
+		return SyncDefinition{}, fmt.Errorf("(path = %s) %w", repoPath, ErrRepoPathTaken)
+	}
+
+	j, err := beginJournal(stateDir(conf))
+	if err != nil {
+		return SyncDefinition{}, err
+	}
+	err = adopt(j, conf, sd, target, repoPath)
+	if err != nil {
+		// put file back so nothing is lost
+		return SyncDefinition{}, errors.Join(err, j.Rollback())
+	}
+
+	// link is owned by ftuck so it can be pruned once entry is removed
+	st, err := LoadState(r.StateDir)
+	if err != nil {
+		return SyncDefinition{}, errors.Join(err, j.Rollback())
+	}
+	run := Run{Command: RUN_ADOPT, Commit: repoCommit(repo), Links: map[string]string{target: st.Links[target]}, syncFile: syncFilePath(conf)}
+	st.SetLink(target, repoPath)
+	err = j.finish(run)
+	if err != nil {
+		return sd, err
+	}
+	return sd, st.Save()
+}
+
+func adopt(j *Journal, conf syncFileGetter, sd SyncDefinition, target, repoPath string) error {
+	slog.Info("adopting", "path", target, "repo path", repoPath)
+	err := j.mkdirAll(filepath.Dir(repoPath), 0)
+	if err != nil {
+		return err
+	}
+	err = j.move(target, repoPath)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("creating link", "source", repoPath, "target", target)
+	err = j.create(target, func() error {
+		return os.Symlink(repoPath, target)
+	})
+	if err != nil {
+		return err
+	}
+	j.done(Action{Kind: ActionAdopt, Source: repoPath, Destination: target})
+
+	err = j.preserve(syncFilePath(conf))
+	if err != nil {
+		return err
+	}
+	err = MaybeCreateAndUpdateSyncFile(conf, sd.Source, sd.Destination)
+	if err != nil {
+		return err
+	}
+	j.done(Action{Kind: ActionUpdateSyncFile, Destination: syncFilePath(conf), Reason: "added " + sd.Destination})
+	return nil
+}
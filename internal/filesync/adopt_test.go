@@ -0,0 +1,73 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestAdopt(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := path.Join(tmpDir, "home")
+	repo := path.Join(tmpDir, "repo")
+	_ = os.MkdirAll(path.Join(home, ".config", "foo"), 0755)
+	_ = os.MkdirAll(path.Join(home, ".config", "dir"), 0755)
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.WriteFile(path.Join(home, ".config", "foo", "bar.toml"), []byte("bar"), 0644)
+	_ = os.WriteFile(path.Join(home, ".config", "dir", "inner"), []byte("inner"), 0644)
+	t.Setenv("HOME", home)
+
+	conf := &confMock{path.Join(repo, SYNC_FILE_NAME)}
+
+	tests := []struct {
+		name     string // description of this test case
+		target   string
+		wantSrc  string
+		wantFile string
+	}{
+		{
+			name:     "single file",
+			target:   path.Join(home, ".config", "foo", "bar.toml"),
+			wantSrc:  ".config/foo/bar.toml",
+			wantFile: ".config/foo/bar.toml",
+		},
+		{
+			name:     "whole directory",
+			target:   path.Join(home, ".config", "dir"),
+			wantSrc:  ".config/dir",
+			wantFile: ".config/dir/inner",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd, err := Adopt(conf, tt.target)
+			if err != nil {
+				t.Fatalf("Adopt() failed: %v", err)
+			}
+			if sd.Source != tt.wantSrc || NewResolver(conf).Destination(sd.Destination) != tt.target {
+				t.Errorf("Adopt() = %+v, want source %s", sd, tt.wantSrc)
+			}
+			if _, err := os.Stat(path.Join(repo, tt.wantFile)); err != nil {
+				t.Errorf("file was not moved into repo: %v", err)
+			}
+			if got, err := os.Readlink(tt.target); err != nil || got != path.Join(repo, tt.wantSrc) {
+				t.Errorf("target is not linked into repo (link: %s, err: %v)", got, err)
+			}
+			if _, err := Adopt(conf, tt.target); err == nil {
+				t.Error("adopting already adopted path succeeded")
+			}
+		})
+	}
+
+	d, err := os.ReadFile(conf.GetSyncFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ReadSchema(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Entries) != len(tests) {
+		t.Errorf("sync file has %d entries, want %d", len(s.Entries), len(tests))
+	}
+}
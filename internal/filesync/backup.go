@@ -0,0 +1,202 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	BACKUP_TIME_FORMAT   string = "20060102T150405"
+	BACKUP_MANIFEST_NAME string = "manifest.yaml"
+	BACKUP_FILES_DIR     string = "files"
+)
+
+var (
+	ErrNoBackups      error = errors.New("no backups found")
+	ErrBackupNotFound error = errors.New("no such backup set")
+)
+
+// BackupEntry maps original location of a file to its place inside backup set
+type BackupEntry struct {
+	Original string `yaml:"original"`
+	Backup   string `yaml:"backup"`
+}
+
+// NewBackupSetPath returns directory of backup set created at given time
+// Sets created in the same second get -N suffix.
+func NewBackupSetPath(backupDir string, t time.Time) string {
+	setDir := filepath.Join(backupDir, t.Format(BACKUP_TIME_FORMAT))
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(setDir); os.IsNotExist(err) {
+			return setDir
+		}
+		setDir = filepath.Join(backupDir, t.Format(BACKUP_TIME_FORMAT)+"-"+strconv.Itoa(i))
+	}
+}
+
+// parseBackupSet returns time and sequence number of backup set with given name
+func parseBackupSet(name string) (time.Time, int, bool) {
+	stamp, suffix, found := strings.Cut(name, "-")
+	t, err := time.Parse(BACKUP_TIME_FORMAT, stamp)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	if !found {
+		return t, 1, true
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil || n < 2 {
+		return time.Time{}, 0, false
+	}
+	return t, n, true
+}
+
+// backupPath returns where original file is kept inside backup set
+func backupPath(setDir, original string) string {
+	return filepath.Join(setDir, BACKUP_FILES_DIR, original)
+}
+
+// backupFile moves original into backup set and records it in set manifest
+func backupFile(j *Journal, setDir, original string) error {
+	manifest, err := readBackupManifest(setDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// set created by failed sync is removed on rollback
+	err = j.create(setDir, func() error {
+		return os.MkdirAll(setDir, 0755)
+	})
+	if err != nil {
+		return err
+	}
+
+	entry := BackupEntry{
+		Original: original,
+		Backup:   backupPath(setDir, original),
+	}
+	slog.Info("backing up", "file", entry.Original, "backup", entry.Backup)
+	err = j.move(entry.Original, entry.Backup)
+	if err != nil {
+		return err
+	}
+
+	err = j.preserve(filepath.Join(setDir, BACKUP_MANIFEST_NAME))
+	if err != nil {
+		return err
+	}
+	manifest = append(manifest, entry)
+	return writeBackupManifest(setDir, manifest)
+}
+
+func readBackupManifest(setDir string) ([]BackupEntry, error) {
+	d, err := os.ReadFile(filepath.Join(setDir, BACKUP_MANIFEST_NAME))
+	if err != nil {
+		return nil, err
+	}
+	res := []BackupEntry{}
+	err = yaml.Unmarshal(d, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func writeBackupManifest(setDir string, manifest []BackupEntry) error {
+	d, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(setDir, BACKUP_MANIFEST_NAME), d, 0644)
+}
+
+// ListBackups returns timestamps of all backup sets from the oldest one
+func ListBackups(backupDir string) ([]string, error) {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	res := []string{}
+	for _, de := range entries {
+		if !de.IsDir() {
+			continue
+		}
+		if _, _, ok := parseBackupSet(de.Name()); !ok {
+			continue
+		}
+		res = append(res, de.Name())
+	}
+	slices.SortFunc(res, func(a, b string) int {
+		ta, na, _ := parseBackupSet(a)
+		tb, nb, _ := parseBackupSet(b)
+		if c := ta.Compare(tb); c != 0 {
+			return c
+		}
+		return na - nb
+	})
+	return res, nil
+}
+
+// RestoreBackup puts every file from backup set back in place.
+// Existing destination is removed only when it is a symlink.
+// Empty timestamp means the latest backup set.
+func RestoreBackup(backupDir string, timestamp string) error {
+	sets, err := ListBackups(backupDir)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("(dir = %s) %w", backupDir, ErrNoBackups)
+	}
+	if timestamp == "" {
+		timestamp = sets[len(sets)-1]
+	}
+	// only names of existing sets are accepted so timestamp cannot point outside backup dir
+	if !slices.Contains(sets, timestamp) {
+		return fmt.Errorf("(dir = %s, set = %s) %w", backupDir, timestamp, ErrBackupNotFound)
+	}
+
+	setDir := filepath.Join(backupDir, timestamp)
+	manifest, err := readBackupManifest(setDir)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range manifest {
+		err := restoreEntry(entry)
+		if err != nil {
+			// restored files are dropped so restore can be repeated for the rest
+			return errors.Join(err, writeBackupManifest(setDir, manifest[i:]))
+		}
+	}
+
+	return os.RemoveAll(setDir)
+}
+
+// restoreEntry moves backed up file back in place of symlink
+func restoreEntry(entry BackupEntry) error {
+	fi, err := os.Lstat(entry.Original)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("(path = %s) cannot restore, file exists and is not a symlink", entry.Original)
+		}
+		err = os.Remove(entry.Original)
+		if err != nil {
+			return err
+		}
+	}
+	slog.Info("restoring", "file", entry.Original, "backup", entry.Backup)
+	return movePath(entry.Backup, entry.Original)
+}
@@ -0,0 +1,151 @@
+package filesync
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcPath := path.Join(tmpDir, "src")
+	destPath := path.Join(tmpDir, "dest")
+	backupDir := path.Join(tmpDir, "backups")
+	_ = os.MkdirAll(srcPath, 0755)
+	_ = os.MkdirAll(destPath, 0755)
+
+	srcF1Name := path.Join(srcPath, "file1")
+	_ = os.WriteFile(srcF1Name, []byte("repo"), 0644)
+	destName := path.Join(destPath, "blocked")
+	_ = os.WriteFile(destName, []byte("original"), 0600)
+
+	s := &Schema{Entries: []SyncDefinition{{Source: "file1", Destination: destName}}}
+	p, err := s.Plan(&confMock{path.Join(srcPath, SYNC_FILE_NAME)}, PlanOptions{BackupDir: backupDir})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if p.Actions[0].Kind != ActionBackupLink {
+		t.Fatalf("action = %s, want %s", p.Actions[0].Kind, ActionBackupLink)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	if got, err := os.Readlink(destName); err != nil || got != srcF1Name {
+		t.Fatalf("destination is not linked to source (link: %s, err: %v)", got, err)
+	}
+	sets, err := ListBackups(backupDir)
+	if err != nil || len(sets) != 1 {
+		t.Fatalf("ListBackups() = %v, %v, want one set", sets, err)
+	}
+	d, err := os.ReadFile(backupPath(path.Join(backupDir, sets[0]), destName))
+	if err != nil || string(d) != "original" {
+		t.Fatalf("backup content = %q, %v", d, err)
+	}
+
+	if err := RestoreBackup(backupDir, ""); err != nil {
+		t.Fatalf("RestoreBackup() failed: %v", err)
+	}
+	fi, err := os.Lstat(destName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		t.Fatal("destination is still a symlink after restore")
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("restored permissions = %v, want 0600", fi.Mode().Perm())
+	}
+	if sets, _ := ListBackups(backupDir); len(sets) != 0 {
+		t.Errorf("backup set was not removed after restore: %v", sets)
+	}
+}
+
+func TestRestoreBackup_Partial(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcPath := path.Join(tmpDir, "src")
+	destPath := path.Join(tmpDir, "dest")
+	backupDir := path.Join(tmpDir, "backups")
+	_ = os.MkdirAll(srcPath, 0755)
+	_ = os.MkdirAll(destPath, 0755)
+	first, second := path.Join(destPath, "first"), path.Join(destPath, "second")
+	for _, p := range []string{first, second} {
+		_ = os.WriteFile(path.Join(srcPath, path.Base(p)), []byte("repo"), 0644)
+		_ = os.WriteFile(p, []byte("original"), 0644)
+	}
+	s := &Schema{Entries: []SyncDefinition{
+		{Source: "first", Destination: first},
+		{Source: "second", Destination: second},
+	}}
+	p, err := s.Plan(&confMock{path.Join(srcPath, SYNC_FILE_NAME)}, PlanOptions{BackupDir: backupDir})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	// second destination was replaced by a regular file after sync
+	_ = os.Remove(second)
+	_ = os.WriteFile(second, []byte("new"), 0644)
+	if err := RestoreBackup(backupDir, ""); err == nil {
+		t.Fatal("RestoreBackup() succeeded over regular file")
+	}
+	if d, _ := os.ReadFile(first); string(d) != "original" {
+		t.Errorf("first file was not restored, content %q", d)
+	}
+	sets, _ := ListBackups(backupDir)
+	manifest, err := readBackupManifest(path.Join(backupDir, sets[0]))
+	if err != nil || len(manifest) != 1 || manifest[0].Original != second {
+		t.Fatalf("manifest after partial restore = %+v, error %v", manifest, err)
+	}
+
+	_ = os.Remove(second)
+	if err := RestoreBackup(backupDir, ""); err != nil {
+		t.Fatalf("repeated RestoreBackup() failed: %v", err)
+	}
+	if d, _ := os.ReadFile(second); string(d) != "original" {
+		t.Errorf("second file was not restored, content %q", d)
+	}
+}
+
+func TestBackupSets(t *testing.T) {
+	backupDir := path.Join(t.TempDir(), "backups")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for range 11 {
+		if err := os.MkdirAll(NewBackupSetPath(backupDir, now), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.MkdirAll(path.Join(backupDir, "20240501T115959"), 0755)
+	_ = os.MkdirAll(path.Join(backupDir, "20240501T120000-x"), 0755)
+
+	sets, err := ListBackups(backupDir)
+	if err != nil || len(sets) != 12 {
+		t.Fatalf("ListBackups() = %v, %v, want 12 sets", sets, err)
+	}
+	want := []string{"20240501T115959", "20240501T120000", "20240501T120000-2", "20240501T120000-10", "20240501T120000-11"}
+	for i, w := range []int{0, 1, 2, 10, 11} {
+		if sets[w] != want[i] {
+			t.Errorf("set %d = %s, want %s", w, sets[w], want[i])
+		}
+	}
+
+	tests := []struct {
+		name      string
+		timestamp string
+		wantErr   error
+	}{
+		{name: "path outside backup dir", timestamp: "../x", wantErr: ErrBackupNotFound},
+		{name: "unknown set", timestamp: "20200101T000000", wantErr: ErrBackupNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RestoreBackup(backupDir, tt.timestamp); !errors.Is(err, tt.wantErr) {
+				t.Errorf("RestoreBackup() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
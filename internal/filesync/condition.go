@@ -0,0 +1,81 @@
+package filesync
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Condition limits entry to machines matching all of its non empty fields.
+// Hosts and env values are glob patterns (see path.Match).
+type Condition struct {
+	Hosts []string          `yaml:"hosts,omitempty"`
+	OS    []string          `yaml:"os,omitempty"`
+	Arch  []string          `yaml:"arch,omitempty"`
+	Env   map[string]string `yaml:"env,omitempty"`
+}
+
+// Facts describe machine on which sync is run
+type Facts struct {
+	Hostname string
+	OS       string
+	Arch     string
+	User     string
+}
+
+func CurrentFacts() Facts {
+	hostname, _ := os.Hostname()
+	username := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	return Facts{
+		Hostname: hostname,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		User:     username,
+	}
+}
+
+func matchesAny(patterns []string, val string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		ok, _ := path.Match(p, val)
+		return ok
+	})
+}
+
+// Check returns false and the reason when entry does not apply to this machine.
+// Nil condition always applies.
+func (c *Condition) Check(f Facts, lookup LookupFunc) (bool, string) {
+	if c == nil {
+		return true, ""
+	}
+	if len(c.Hosts) > 0 && !matchesAny(c.Hosts, f.Hostname) {
+		return false, fmt.Sprintf("host %s not in [%s]", f.Hostname, strings.Join(c.Hosts, " "))
+	}
+	if len(c.OS) > 0 && !slices.Contains(c.OS, f.OS) {
+		return false, fmt.Sprintf("os %s not in [%s]", f.OS, strings.Join(c.OS, " "))
+	}
+	if len(c.Arch) > 0 && !slices.Contains(c.Arch, f.Arch) {
+		return false, fmt.Sprintf("arch %s not in [%s]", f.Arch, strings.Join(c.Arch, " "))
+	}
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		val, ok := lookup(k)
+		if !ok {
+			return false, fmt.Sprintf("env %s not set", k)
+		}
+		if match, _ := path.Match(c.Env[k], val); !match {
+			return false, fmt.Sprintf("env %s=%s does not match %s", k, val, c.Env[k])
+		}
+	}
+	return true, ""
+}
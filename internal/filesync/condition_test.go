@@ -0,0 +1,47 @@
+package filesync
+
+import (
+	"testing"
+)
+
+func TestCondition_Check(t *testing.T) {
+	facts := Facts{Hostname: "build-01", OS: "linux", Arch: "amd64"}
+	env := map[string]string{"DESKTOP_SESSION": "sway"}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		name string // description of this test case
+		cond *Condition
+		want bool
+	}{
+		{name: "no condition", cond: nil, want: true},
+		{name: "empty condition", cond: &Condition{}, want: true},
+		{name: "host pattern matches", cond: &Condition{Hosts: []string{"laptop", "build-*"}}, want: true},
+		{name: "host does not match", cond: &Condition{Hosts: []string{"laptop"}}, want: false},
+		{name: "os matches", cond: &Condition{OS: []string{"darwin", "linux"}}, want: true},
+		{name: "os does not match", cond: &Condition{OS: []string{"darwin"}}, want: false},
+		{name: "arch does not match", cond: &Condition{Arch: []string{"arm64"}}, want: false},
+		{name: "env matches", cond: &Condition{Env: map[string]string{"DESKTOP_SESSION": "sw*"}}, want: true},
+		{name: "env differs", cond: &Condition{Env: map[string]string{"DESKTOP_SESSION": "gnome"}}, want: false},
+		{name: "env not set", cond: &Condition{Env: map[string]string{"DISPLAY": "*"}}, want: false},
+		{
+			name: "all fields must match",
+			cond: &Condition{OS: []string{"linux"}, Hosts: []string{"laptop"}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.cond.Check(facts, lookup)
+			if got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+			if !got && reason == "" {
+				t.Error("Check() returned no reason for skipped entry")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package filesync
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// movePath renames src to dst falling back to copy and delete
+// when both paths are on different devices
+func movePath(src, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	err = copyPath(src, dst)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyPath copies regular file, symlink or whole directory tree keeping permissions
+func copyPath(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case fi.IsDir():
+		err := os.MkdirAll(dst, fi.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, de := range entries {
+			err := copyPath(filepath.Join(src, de.Name()), filepath.Join(dst, de.Name()))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return copyFile(src, dst, fi.Mode().Perm())
+	}
+}
+
+// replacePath copies src over dst through temporary path next to dst,
+// dst is left untouched when copy fails
+func replacePath(src, dst string) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	staged := filepath.Join(tmp, "new")
+	err = copyPath(src, staged)
+	if err != nil {
+		return err
+	}
+	old := filepath.Join(tmp, "old")
+	err = os.Rename(dst, old)
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Rename(staged, dst)
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Rename(staged, dst)
+	if err != nil {
+		return errors.Join(err, os.Rename(old, dst))
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+	return os.Chmod(dst, perm)
+}
+
+// writeFileAtomic replaces content of p through temporary file in the same directory,
+// existing file keeps its permissions and new one is created with perm
+func writeFileAtomic(p string, d []byte, perm os.FileMode) error {
+	if fi, err := os.Stat(p); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(d)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(f.Name(), perm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,147 @@
+package filesync
+
+import (
+	"net"
+	"os"
+	"path"
+	"testing"
+)
+
+type confStateMock struct {
+	confMock
+	stateDir string
+}
+
+// GetStateDir implements stateDirGetter.
+func (c *confStateMock) GetStateDir() string {
+	return c.stateDir
+}
+
+func TestSchema_PlanCopyMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	src := path.Join(repo, "config")
+	dest := path.Join(home, "config")
+	_ = os.WriteFile(src, []byte("v1"), 0600)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+	s := &Schema{Entries: []SyncDefinition{{Source: "config", Destination: dest, Mode: MODE_COPY}}}
+
+	sync := func(want ActionKind) {
+		t.Helper()
+		p, err := s.Plan(conf, PlanOptions{})
+		if err != nil {
+			t.Fatalf("Plan() failed: %v", err)
+		}
+		if p.Actions[0].Kind != want {
+			t.Fatalf("action = %s (%s), want %s", p.Actions[0].Kind, p.Actions[0].Reason, want)
+		}
+		if err := p.Execute(); err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+	}
+	status := func(want EntryState, wantDrift Drift) {
+		t.Helper()
+		statuses, err := s.Status(conf)
+		if err != nil {
+			t.Fatalf("Status() failed: %v", err)
+		}
+		if statuses[0].State != want || statuses[0].Drift != wantDrift {
+			t.Fatalf("status = %s (%s), want %s (%s)", statuses[0].State, statuses[0].Drift, want, wantDrift)
+		}
+	}
+
+	sync(ActionCopy)
+	fi, err := os.Lstat(dest)
+	if err != nil || fi.Mode()&os.ModeSymlink != 0 || fi.Mode().Perm() != 0600 {
+		t.Fatalf("destination is not a copy with source permissions (err: %v)", err)
+	}
+	status(StateCopied, "")
+	sync(ActionSkip)
+
+	_ = os.WriteFile(src, []byte("v2"), 0600)
+	status(StateDrifted, DriftSource)
+	sync(ActionCopy)
+	if d, _ := os.ReadFile(dest); string(d) != "v2" {
+		t.Fatalf("destination content = %q, want v2", d)
+	}
+
+	_ = os.WriteFile(dest, []byte("local edit"), 0600)
+	status(StateDrifted, DriftDestination)
+	sync(ActionConflict)
+
+	_ = os.WriteFile(src, []byte("v3"), 0600)
+	status(StateDrifted, DriftBoth)
+}
+
+func TestSchema_PlanHardlinkMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := path.Join(tmpDir, "config")
+	dest := path.Join(tmpDir, "hardlink")
+	_ = os.WriteFile(src, []byte("v1"), 0644)
+	_ = os.Symlink(src, dest)
+	conf := &confMock{path.Join(tmpDir, SYNC_FILE_NAME)}
+	s := &Schema{Entries: []SyncDefinition{
+		{Source: "config", Destination: dest, Mode: MODE_HARDLINK},
+		{Source: tmpDir, Destination: path.Join(tmpDir, "dir"), Mode: MODE_HARDLINK},
+	}}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if p.Actions[0].Kind != ActionHardlink || p.Actions[1].Kind != ActionConflict {
+		t.Fatalf("Plan() = %+v, want hardlink and conflict", p)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	statuses, err := s.Status(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].State != StateLinked {
+		t.Errorf("status after sync = %s, want %s", statuses[0].State, StateLinked)
+	}
+}
+
+func TestReplacePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	src := path.Join(tmpDir, "deployed")
+	dst := path.Join(repo, "nvim")
+	_ = os.MkdirAll(dst, 0755)
+	_ = os.WriteFile(path.Join(dst, "init.lua"), []byte("repo"), 0644)
+	_ = os.MkdirAll(src, 0755)
+	_ = os.WriteFile(path.Join(src, "init.lua"), []byte("deployed"), 0644)
+	// sockets cannot be copied
+	l, err := net.Listen("unix", path.Join(src, "sock"))
+	if err != nil {
+		t.Skipf("cannot create socket: %v", err)
+	}
+
+	if err := replacePath(src, dst); err == nil {
+		t.Fatal("replacePath() succeeded copying socket")
+	}
+	if d, _ := os.ReadFile(path.Join(dst, "init.lua")); string(d) != "repo" {
+		t.Errorf("destination after failed copy = %q, want it untouched", d)
+	}
+	if entries, _ := os.ReadDir(repo); len(entries) != 1 {
+		t.Errorf("repo after failed copy = %v, want no temporary files", entries)
+	}
+
+	l.Close()
+	_ = os.Remove(path.Join(src, "sock"))
+	if err := replacePath(src, dst); err != nil {
+		t.Fatalf("replacePath() failed: %v", err)
+	}
+	if d, _ := os.ReadFile(path.Join(dst, "init.lua")); string(d) != "deployed" {
+		t.Errorf("destination after copy = %q, want %q", d, "deployed")
+	}
+	if entries, _ := os.ReadDir(repo); len(entries) != 1 {
+		t.Errorf("repo after copy = %v, want no temporary files", entries)
+	}
+}
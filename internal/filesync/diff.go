@@ -0,0 +1,220 @@
+package filesync
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DIFF_CONTEXT is number of unchanged lines around changes in unified diff
+const DIFF_CONTEXT int = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+func splitLines(d []byte) []string {
+	if len(d) == 0 {
+		return []string{}
+	}
+	lines := strings.SplitAfter(string(d), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes edit script turning a into b using longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is length of LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := []diffOp{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// UnifiedDiff returns diff of a and b in unified format, empty when both are equal
+func UnifiedDiff(aName string, bName string, a []byte, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", aName, bName)
+
+	// line numbers in a and b before every op
+	aLine, bLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if op.kind != '+' {
+			aLine[k+1]++
+		}
+		if op.kind != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		// hunk starts with context before first change and ends when
+		// there are more than 2*DIFF_CONTEXT unchanged lines in a row
+		start := max(0, k-DIFF_CONTEXT)
+		end := k
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*DIFF_CONTEXT {
+				end = min(end+DIFF_CONTEXT, len(ops))
+				break
+			}
+			end = next
+		}
+
+		aCount, bCount := aLine[end]-aLine[start], bLine[end]-bLine[start]
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aLine[start], aCount), hunkRange(bLine[start], bCount))
+		for _, op := range ops[start:end] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+	return buf.String()
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// DiffEntry returns unified diff of copied entry source and destination.
+// Templated and encrypted entries are compared using content written to destination.
+func DiffEntry(es EntryStatus) (string, error) {
+	if es.content == nil {
+		return DiffPaths(es.Source, es.Destination)
+	}
+	d, err := readIfExists(es.Destination)
+	if err != nil {
+		return "", err
+	}
+	label := es.Source + " (decrypted)"
+	if es.Definition.Template {
+		label = es.Source + " (rendered)"
+	}
+	return UnifiedDiff(label, es.Destination, es.content, d), nil
+}
+
+// DiffPaths returns unified diff of two files or of every file in two directory trees
+func DiffPaths(src string, dest string) (string, error) {
+	files, err := treeFiles(src)
+	if err != nil {
+		return "", err
+	}
+	destFiles, err := treeFiles(dest)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range destFiles {
+		if !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	slices.Sort(files)
+
+	buf := bytes.NewBuffer(nil)
+	for _, f := range files {
+		a, err := readIfExists(filepath.Join(src, f))
+		if err != nil {
+			return "", err
+		}
+		b, err := readIfExists(filepath.Join(dest, f))
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(UnifiedDiff(filepath.Join(src, f), filepath.Join(dest, f), a, b))
+	}
+	return buf.String(), nil
+}
+
+// treeFiles lists files relative to root, root itself is "." when it is a file
+func treeFiles(root string) ([]string, error) {
+	res := []string{}
+	err := filepath.WalkDir(root, func(p string, de fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if de.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		res = append(res, rel)
+		return nil
+	})
+	return res, err
+}
+
+func readIfExists(p string) ([]byte, error) {
+	d, err := os.ReadFile(p)
+	if err != nil && os.IsNotExist(err) {
+		return []byte{}, nil
+	}
+	return d, err
+}
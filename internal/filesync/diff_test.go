@@ -0,0 +1,117 @@
+package filesync
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "added to empty",
+			a:    "",
+			b:    "x\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name: "missing newline at end",
+			a:    "a\n",
+			b:    "a\nb",
+			want: "--- a\n+++ b\n@@ -1 +1,2 @@\n a\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name: "distant changes in separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+y\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("a", "b", []byte(tt.a), []byte(tt.b))
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_Pull(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := path.Join(tmpDir, "config")
+	dest := path.Join(tmpDir, "deployed")
+	_ = os.WriteFile(src, []byte("v1\n"), 0644)
+	conf := &confStateMock{confMock{path.Join(tmpDir, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+	s := &Schema{Entries: []SyncDefinition{{Source: "config", Destination: dest, Mode: MODE_COPY}}}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.WriteFile(dest, []byte("v1\nlocal\n"), 0644)
+	diff, err := DiffPaths(src, dest)
+	if err != nil || diff == "" {
+		t.Fatalf("DiffPaths() = %q, %v, want diff", diff, err)
+	}
+
+	pulled, err := s.Pull(conf, []string{dest}, false)
+	if err != nil {
+		t.Fatalf("Pull() failed: %v", err)
+	}
+	if len(pulled) != 1 {
+		t.Fatalf("Pull() pulled %d entries, want 1", len(pulled))
+	}
+	if d, _ := os.ReadFile(src); string(d) != "v1\nlocal\n" {
+		t.Errorf("source content after pull = %q", d)
+	}
+	statuses, _ := s.Status(conf)
+	if statuses[0].State != StateCopied {
+		t.Errorf("status after pull = %s, want %s", statuses[0].State, StateCopied)
+	}
+
+	_ = os.WriteFile(dest, []byte("dest\n"), 0644)
+	_ = os.WriteFile(src, []byte("src\n"), 0644)
+	if _, err := s.Pull(conf, nil, false); err == nil {
+		t.Fatal("Pull() succeeded when both sides changed")
+	}
+	if _, err := s.Pull(conf, nil, true); err != nil {
+		t.Fatalf("Pull() with force failed: %v", err)
+	}
+	if d, _ := os.ReadFile(src); string(d) != "dest\n" {
+		t.Errorf("source content after forced pull = %q", d)
+	}
+
+	// destination which was not copied by ftuck
+	other := path.Join(tmpDir, "other-deployed")
+	_ = os.WriteFile(other, []byte("other\n"), 0644)
+	_ = os.WriteFile(path.Join(tmpDir, "other"), []byte("repo\n"), 0644)
+	s.Entries = append(s.Entries, SyncDefinition{Source: "other", Destination: other, Mode: MODE_COPY})
+	_, err = s.Pull(conf, []string{other}, false)
+	if !errors.Is(err, ErrPullBlocked) || !strings.Contains(err.Error(), "destination differs from source") {
+		t.Errorf("Pull() of blocked entry error = %v, want %v with reason", err, ErrPullBlocked)
+	}
+}
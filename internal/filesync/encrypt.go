@@ -0,0 +1,257 @@
+package filesync
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ENCRYPTED_HEADER starts every file encrypted by ftuck.
+// Files are encrypted with X25519 key agreement, HKDF-SHA256 and AES-256-GCM.
+const ENCRYPTED_HEADER string = "ftuck-encrypted-v1"
+
+const (
+	hkdfInfo         string = "ftuck encrypted file v1"
+	hkdfChecksumInfo string = "ftuck checksum v1"
+)
+
+var (
+	ErrNotEncrypted    = errors.New("file is not encrypted by ftuck")
+	ErrNoIdentity      = errors.New("no identity file configured, run keygen first")
+	ErrDecrypt         = errors.New("cannot decrypt, file is damaged or encrypted for another identity")
+	ErrInvalidIdentity = errors.New("invalid identity file")
+)
+
+type identityGetter interface {
+	GetIdentityFile() string
+}
+
+// identityFile returns identity path from configuration, empty when configuration does not provide it
+func identityFile(conf syncFileGetter) string {
+	ig, ok := conf.(identityGetter)
+	if !ok {
+		return ""
+	}
+	return ig.GetIdentityFile()
+}
+
+// Identity is a private key used to decrypt secrets. Files are encrypted
+// for its public key so encrypting does not need the private part of the identity,
+// but ftuck keeps just one identity per machine.
+type Identity struct {
+	key *ecdh.PrivateKey
+}
+
+func GenerateIdentity() (*Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{key}, nil
+}
+
+// LoadIdentity reads identity file written by Save
+func LoadIdentity(p string) (*Identity, error) {
+	if p == "" {
+		return nil, ErrNoIdentity
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("(path = %s) %w", p, ErrInvalidIdentity)
+		}
+		key, err := ecdh.X25519().NewPrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("(path = %s) %w", p, ErrInvalidIdentity)
+		}
+		return &Identity{key}, nil
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("(path = %s) %w", p, ErrInvalidIdentity)
+}
+
+// Save writes identity readable only by the owner. Existing file is never overwritten.
+func (id *Identity) Save(p string) error {
+	err := os.MkdirAll(filepath.Dir(p), 0700)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "# created: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(f, "# public key: %s\n", id.PublicKey())
+	fmt.Fprintln(f, base64.StdEncoding.EncodeToString(id.key.Bytes()))
+	return f.Close()
+}
+
+// PublicKey returns base64 encoded public key of identity
+func (id *Identity) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(id.key.PublicKey().Bytes())
+}
+
+// IsEncrypted checks if data starts with ENCRYPTED_HEADER
+func IsEncrypted(d []byte) bool {
+	return bytes.HasPrefix(d, []byte(ENCRYPTED_HEADER+"\n"))
+}
+
+func fileKey(shared []byte, ephemeral []byte, recipient []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	key, err := hkdf.Key(sha256.New, shared, salt, hkdfInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts plain text for the identity. Output is text:
+// header, ephemeral public key and sealed content each on its own line.
+func (id *Identity) Encrypt(plain []byte) ([]byte, error) {
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	recipient := id.key.PublicKey()
+	shared, err := eph.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := fileKey(shared, eph.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("%s\n%s\n", ENCRYPTED_HEADER, base64.StdEncoding.EncodeToString(eph.PublicKey().Bytes()))
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(header))
+	return []byte(header + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// Decrypt opens data produced by Encrypt
+func (id *Identity) Decrypt(d []byte) ([]byte, error) {
+	if !IsEncrypted(d) {
+		return nil, ErrNotEncrypted
+	}
+	lines := strings.Split(strings.TrimRight(string(d), "\n"), "\n")
+	if len(lines) != 3 {
+		return nil, ErrDecrypt
+	}
+	ephRaw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	sealed, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	eph, err := ecdh.X25519().NewPublicKey(ephRaw)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	shared, err := id.key.ECDH(eph)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	aead, err := fileKey(shared, ephRaw, id.key.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	header := lines[0] + "\n" + lines[1] + "\n"
+	nonce, ct := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open([]byte{}, nonce, ct, []byte(header))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// EncryptFile writes plain text encrypted for identity to p
+func EncryptFile(id *Identity, plain []byte, p string) error {
+	d, err := id.Encrypt(plain)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p, d, 0644)
+}
+
+// DecryptFile returns decrypted content of file p
+func DecryptFile(id *Identity, p string) ([]byte, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := id.Decrypt(d)
+	if err != nil {
+		return nil, fmt.Errorf("(path = %s) %w", p, err)
+	}
+	return plain, nil
+}
+
+// decrypt opens encrypted source with identity from configuration
+func (r *Resolver) decrypt(d []byte) ([]byte, error) {
+	id, err := LoadIdentity(r.IdentityFile)
+	if err != nil {
+		return nil, err
+	}
+	return id.Decrypt(d)
+}
+
+// checksum returns checksum of decrypted content keyed with identity,
+// so state does not keep plain hash of secret which guesses could be checked against
+func (id *Identity) checksum(plain []byte) (string, error) {
+	key, err := hkdf.Key(sha256.New, id.key.Bytes(), nil, hkdfChecksumInfo, 32)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(plain)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// secretChecksum returns keyed checksum of decrypted content with identity from configuration
+func (r *Resolver) secretChecksum(plain []byte) (string, error) {
+	id, err := LoadIdentity(r.IdentityFile)
+	if err != nil {
+		return "", err
+	}
+	return id.checksum(plain)
+}
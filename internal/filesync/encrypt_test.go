@@ -0,0 +1,159 @@
+package filesync
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+type confIdentityMock struct {
+	confStateMock
+	identityFile string
+}
+
+// GetIdentityFile implements identityGetter.
+func (c *confIdentityMock) GetIdentityFile() string {
+	return c.identityFile
+}
+
+func TestIdentity_EncryptDecrypt(t *testing.T) {
+	tmpDir := t.TempDir()
+	idPath := path.Join(tmpDir, "keys", "identity")
+	id, err := GenerateIdentity()
+	if err != nil {
+		t.Fatalf("GenerateIdentity() failed: %v", err)
+	}
+	if err := id.Save(idPath); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if fi, _ := os.Stat(idPath); fi.Mode().Perm() != 0600 {
+		t.Errorf("identity permissions = %v, want 0600", fi.Mode().Perm())
+	}
+	if err := id.Save(idPath); err == nil {
+		t.Errorf("Save() overwrote existing identity")
+	}
+	loaded, err := LoadIdentity(idPath)
+	if err != nil {
+		t.Fatalf("LoadIdentity() failed: %v", err)
+	}
+	other, _ := GenerateIdentity()
+
+	enc, err := id.Encrypt([]byte("machine example.com password secret\n"))
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+	if strings.Contains(string(enc), "secret") || !IsEncrypted(enc) {
+		t.Fatalf("Encrypt() = %q", enc)
+	}
+	tampered := []byte(strings.Replace(string(enc), ENCRYPTED_HEADER+"\n", ENCRYPTED_HEADER+"\n\n", 1))
+
+	tests := []struct {
+		name    string
+		id      *Identity
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{"loaded identity", loaded, enc, "machine example.com password secret\n", nil},
+		{"other identity", other, enc, "", ErrDecrypt},
+		{"tampered", id, tampered, "", ErrDecrypt},
+		{"plain text", id, []byte("secret"), "", ErrNotEncrypted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.id.Decrypt(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decrypt() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadIdentity(""); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("LoadIdentity(\"\") error = %v, want %v", err, ErrNoIdentity)
+	}
+}
+
+func TestSchema_PlanEncrypted(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	src := path.Join(repo, "netrc")
+	dest := path.Join(home, ".netrc")
+	idPath := path.Join(tmpDir, "identity")
+	id, _ := GenerateIdentity()
+	_ = id.Save(idPath)
+	if err := EncryptFile(id, []byte("password one\n"), src); err != nil {
+		t.Fatalf("EncryptFile() failed: %v", err)
+	}
+	conf := &confIdentityMock{confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}, idPath}
+	s := &Schema{Entries: []SyncDefinition{{Source: "netrc", Destination: dest, Encrypted: true}}}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	fi, err := os.Stat(dest)
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("destination is not readable only by owner (err: %v)", err)
+	}
+	if d, _ := os.ReadFile(dest); string(d) != "password one\n" {
+		t.Fatalf("destination = %q", d)
+	}
+
+	statuses, _ := s.Status(conf)
+	if statuses[0].State != StateCopied {
+		t.Fatalf("status = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StateCopied)
+	}
+	st, _ := LoadState(path.Join(tmpDir, "state"))
+	if sum, _ := id.checksum([]byte("password one\n")); st.Checksums[dest] != sum {
+		t.Errorf("recorded checksum = %q, want checksum keyed with identity %q", st.Checksums[dest], sum)
+	}
+	if fi, err := os.Stat(path.Join(tmpDir, "state", STATE_FILE_NAME)); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("state file is not readable only by owner (err: %v)", err)
+	}
+
+	// changed secret is encrypted back into repo
+	_ = os.WriteFile(dest, []byte("password two\n"), 0600)
+	pulled, err := s.Pull(conf, nil, false)
+	if err != nil || len(pulled) != 1 {
+		t.Fatalf("Pull() = %d entries, error %v", len(pulled), err)
+	}
+	plain, err := DecryptFile(id, src)
+	if err != nil || string(plain) != "password two\n" {
+		t.Fatalf("DecryptFile() = %q, error %v", plain, err)
+	}
+	statuses, _ = s.Status(conf)
+	if statuses[0].State != StateCopied {
+		t.Fatalf("status after pull = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StateCopied)
+	}
+	// changed on both sides is still told apart with keyed checksums
+	_ = os.WriteFile(dest, []byte("password three\n"), 0600)
+	_ = EncryptFile(id, []byte("password four\n"), src)
+	statuses, _ = s.Status(conf)
+	if statuses[0].State != StateDrifted || statuses[0].Drift != DriftBoth {
+		t.Errorf("status after both changed = %s (%s), want %s", statuses[0].State, statuses[0].Drift, DriftBoth)
+	}
+
+	s.Entries[0].Perm = "0644"
+	statuses, _ = s.Status(conf)
+	if statuses[0].State != StateBlocked || !strings.Contains(statuses[0].Reason, "readable by others") {
+		t.Errorf("status with perm 0644 = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StateBlocked)
+	}
+	s.Entries[0].Perm = ""
+
+	conf.identityFile = path.Join(tmpDir, "missing")
+	statuses, _ = s.Status(conf)
+	if statuses[0].State != StateBlocked || !strings.Contains(statuses[0].Reason, "decrypting") {
+		t.Errorf("status without identity = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StateBlocked)
+	}
+}
@@ -0,0 +1,49 @@
+package filesync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LookupFunc returns value of a variable and whether it is set
+type LookupFunc func(key string) (string, bool)
+
+// ExpandPath expands leading `~` to home dir and every $VAR, ${VAR}
+// and ${VAR:-fallback} using lookup. Leading `~` of fallback is expanded too.
+// Unset variables without fallback expand to empty string.
+func ExpandPath(p string, home string, lookup LookupFunc) string {
+	p = expandTilde(p, home)
+
+	return os.Expand(p, func(key string) string {
+		name, fallback, hasFallback := strings.Cut(key, ":-")
+		val, ok := lookup(name)
+		if hasFallback && (!ok || val == "") {
+			return expandTilde(fallback, home)
+		}
+		return val
+	})
+}
+
+// expandTilde replaces leading `~` with home dir
+func expandTilde(p string, home string) string {
+	if p == "~" {
+		return home
+	}
+	if strings.HasPrefix(p, "~/") {
+		return filepath.Join(home, p[2:])
+	}
+	return p
+}
+
+// portablePath replaces home dir prefix of absolute path with `~`
+func portablePath(p string, home string) string {
+	if home == "" || !isWithin(home, p) {
+		return p
+	}
+	rel, _ := filepath.Rel(home, p)
+	if rel == "." {
+		return "~"
+	}
+	return "~/" + rel
+}
@@ -0,0 +1,62 @@
+package filesync
+
+import (
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	env := map[string]string{
+		"HOME":            "/home/user",
+		"XDG_CONFIG_HOME": "/home/user/.xdg",
+		"EMPTY":           "",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		name string // description of this test case
+		path string
+		want string
+	}{
+		{name: "tilde alone", path: "~", want: "/home/user"},
+		{name: "tilde prefix", path: "~/.bashrc", want: "/home/user/.bashrc"},
+		{name: "tilde in the middle is kept", path: "/tmp/~/x", want: "/tmp/~/x"},
+		{name: "plain variable", path: "$HOME/.bashrc", want: "/home/user/.bashrc"},
+		{name: "braced variable", path: "${XDG_CONFIG_HOME}/nvim", want: "/home/user/.xdg/nvim"},
+		{name: "fallback for unset variable", path: "${XDG_DATA_HOME:-~/.local/share}/app", want: "/home/user/.local/share/app"},
+		{name: "fallback for empty variable", path: "${EMPTY:-/opt}/app", want: "/opt/app"},
+		{name: "fallback not used when set", path: "${XDG_CONFIG_HOME:-/nope}/nvim", want: "/home/user/.xdg/nvim"},
+		{name: "unset variable without fallback", path: "/a/${NOPE}b", want: "/a/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExpandPath(tt.path, env["HOME"], lookup)
+			if got != tt.want {
+				t.Errorf("ExpandPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortablePath(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		path string
+		want string
+	}{
+		{name: "home itself", path: "/home/user", want: "~"},
+		{name: "under home", path: "/home/user/.config/nvim", want: "~/.config/nvim"},
+		{name: "outside home", path: "/etc/hosts", want: "/etc/hosts"},
+		{name: "sibling with common prefix", path: "/home/user2/.bashrc", want: "/home/user2/.bashrc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := portablePath(tt.path, "/home/user")
+			if got != tt.want {
+				t.Errorf("portablePath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
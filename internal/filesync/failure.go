@@ -0,0 +1,67 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrConflict     = errors.New("conflict")
+	ErrInvalidEntry = errors.New("invalid entry")
+)
+
+// FailureKind groups failures by their cause
+type FailureKind string
+
+const (
+	FAILURE_CONFLICT   FailureKind = "conflict"
+	FAILURE_PERMISSION FailureKind = "permission denied"
+	FAILURE_MISSING    FailureKind = "missing file"
+	FAILURE_EXISTS     FailureKind = "file exists"
+	FAILURE_HOOK       FailureKind = "hook failed"
+	FAILURE_INVALID    FailureKind = "invalid sync file"
+	FAILURE_OTHER      FailureKind = "other"
+)
+
+// Failure is an entry which could not be synced when sync keeps going after errors
+type Failure struct {
+	Kind FailureKind
+	// Destination of failed entry, empty for hooks of sync file
+	Destination string
+	Err         error
+}
+
+func (f Failure) Error() string {
+	if f.Destination == "" {
+		return f.Err.Error()
+	}
+	return fmt.Sprintf("(target = %s) %v", f.Destination, f.Err)
+}
+
+func (f Failure) Unwrap() error {
+	return f.Err
+}
+
+func newFailure(a Action, err error) Failure {
+	kind := FAILURE_OTHER
+	switch {
+	case errors.Is(err, ErrConflict):
+		kind = FAILURE_CONFLICT
+	case errors.Is(err, ErrHookFailed):
+		kind = FAILURE_HOOK
+	case errors.Is(err, ErrInvalidEntry):
+		kind = FAILURE_INVALID
+	case errors.Is(err, os.ErrPermission):
+		kind = FAILURE_PERMISSION
+	case errors.Is(err, os.ErrNotExist):
+		kind = FAILURE_MISSING
+	case errors.Is(err, os.ErrExist):
+		kind = FAILURE_EXISTS
+	}
+	return Failure{
+		Kind:        kind,
+		Destination: a.Destination,
+		Err:         err,
+	}
+}
@@ -0,0 +1,168 @@
+package filesync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestPlan_ExecuteKeepGoing(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	stateDir := path.Join(tmpDir, "state")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	for _, f := range []string{"bashrc", "vimrc", "token", "gitconfig"} {
+		_ = os.WriteFile(path.Join(repo, f), []byte(f), 0644)
+	}
+	_ = os.WriteFile(path.Join(home, ".vimrc"), []byte("existing"), 0644)
+	// parent of destination is a file
+	_ = os.WriteFile(path.Join(home, ".config"), []byte("file"), 0644)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, stateDir}
+	s := &Schema{
+		Entries: []SyncDefinition{
+			{Source: "bashrc", Destination: ".bashrc"},
+			{Source: "vimrc", Destination: ".vimrc"},
+			{Source: "token", Destination: ".token", Mode: MODE_COPY, Hooks: &Hooks{After: []string{"exit 1"}}},
+			{Source: "vimrc", Destination: ".exrc", Hooks: &Hooks{After: []string{"exit 1"}}},
+			{Source: "gitconfig", Destination: ".config/git/config"},
+		},
+	}
+	t.Setenv("HOME", home)
+
+	p, err := s.Plan(conf, PlanOptions{KeepGoing: true})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	failures, err := p.ExecuteKeepGoing(context.Background())
+	if !errors.Is(err, ErrConflict) || !errors.Is(err, ErrHookFailed) {
+		t.Errorf("ExecuteKeepGoing() error = %v, want joined failures", err)
+	}
+
+	want := map[string]FailureKind{
+		path.Join(home, ".vimrc"):             FAILURE_CONFLICT,
+		path.Join(home, ".token"):             FAILURE_HOOK,
+		path.Join(home, ".exrc"):              FAILURE_HOOK,
+		path.Join(home, ".config/git/config"): FAILURE_OTHER,
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("ExecuteKeepGoing() failures = %v, want %d", failures, len(want))
+	}
+	for _, f := range failures {
+		if want[f.Destination] != f.Kind {
+			t.Errorf("failure of %s has kind %q, want %q", f.Destination, f.Kind, want[f.Destination])
+		}
+	}
+
+	if target, _ := os.Readlink(path.Join(home, ".bashrc")); target != path.Join(repo, "bashrc") {
+		t.Errorf(".bashrc links to %q, want entries after failures synced", target)
+	}
+	if _, err := os.Lstat(path.Join(home, ".token")); !os.IsNotExist(err) {
+		t.Errorf("copy of entry with failed hook was not rolled back")
+	}
+	if d, _ := os.ReadFile(path.Join(home, ".vimrc")); string(d) != "existing" {
+		t.Errorf("conflicting file was changed to %q", d)
+	}
+	if _, err := os.Stat(path.Join(stateDir, JOURNAL_DIR_NAME)); !os.IsNotExist(err) {
+		t.Errorf("journal was not removed")
+	}
+	st, _ := LoadState(stateDir)
+	if _, ok := st.Checksums[path.Join(home, ".token")]; ok {
+		t.Errorf("checksum of rolled back copy was saved")
+	}
+	if _, ok := st.Links[path.Join(home, ".exrc")]; ok {
+		t.Errorf("rolled back link was saved")
+	}
+}
+
+func TestPlan_KeepGoingInvalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "bashrc"), []byte("bashrc"), 0644)
+	data := []byte(`version: 2
+colour: blue
+entries:
+  - src: ""
+    dest: linked_repo
+  - src: bashrc
+    dest: .bashrc
+    perms: "0600"
+  - src: bashrc
+    dest: .profile
+`)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+	t.Setenv("HOME", home)
+
+	issues, err := ValidateSchema(data, NewResolver(conf))
+	if err != nil {
+		t.Fatalf("ValidateSchema() failed: %v", err)
+	}
+	inEntry := 0
+	for _, i := range issues {
+		if i.InEntry() {
+			inEntry++
+		}
+	}
+	if len(issues) != 3 || inEntry != 2 {
+		t.Fatalf("ValidateSchema() = %v, want unknown key of sync file and issues of 2 entries", issues)
+	}
+
+	s, _ := ReadSchema(data)
+	if _, err := s.Plan(conf, PlanOptions{Issues: issues}); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("Plan() error = %v, want %v", err, ErrInvalidEntry)
+	}
+	p, err := s.Plan(conf, PlanOptions{KeepGoing: true, Issues: issues})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	failures, _ := p.ExecuteKeepGoing(context.Background())
+	want := map[string]FailureKind{
+		path.Join(home, "linked_repo"): FAILURE_INVALID,
+		path.Join(home, ".bashrc"):     FAILURE_INVALID,
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("ExecuteKeepGoing() failures = %v, want %d", failures, len(want))
+	}
+	for _, f := range failures {
+		if want[f.Destination] != f.Kind {
+			t.Errorf("failure of %s has kind %q, want %q", f.Destination, f.Kind, want[f.Destination])
+		}
+	}
+	for _, f := range []string{"linked_repo", ".bashrc"} {
+		if _, err := os.Lstat(path.Join(home, f)); !os.IsNotExist(err) {
+			t.Errorf("invalid entry %s was synced", f)
+		}
+	}
+	if target, _ := os.Readlink(path.Join(home, ".profile")); target != path.Join(repo, "bashrc") {
+		t.Errorf(".profile links to %q, want valid entries synced", target)
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FailureKind
+	}{
+		{"conflict", ErrConflict, FAILURE_CONFLICT},
+		{"hook", ErrHookFailed, FAILURE_HOOK},
+		{"invalid", ErrInvalidEntry, FAILURE_INVALID},
+		{"permission", &os.PathError{Op: "open", Path: "/x", Err: os.ErrPermission}, FAILURE_PERMISSION},
+		{"missing", &os.PathError{Op: "open", Path: "/x", Err: os.ErrNotExist}, FAILURE_MISSING},
+		{"exists", &os.PathError{Op: "open", Path: "/x", Err: os.ErrExist}, FAILURE_EXISTS},
+		{"other", errors.New("boom"), FAILURE_OTHER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newFailure(Action{}, tt.err); got.Kind != tt.want {
+				t.Errorf("newFailure() kind = %q, want %q", got.Kind, tt.want)
+			}
+		})
+	}
+}
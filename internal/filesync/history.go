@@ -0,0 +1,374 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HISTORY_DIR_NAME is directory in state dir with finished runs which can be undone
+const HISTORY_DIR_NAME string = "history"
+
+const (
+	RUN_FILE_NAME string = "run.yaml"
+	// HISTORY_LIMIT is number of newest runs kept in history
+	HISTORY_LIMIT int = 50
+)
+
+// Commands recorded in history
+const (
+	RUN_SYNC   string = "sync"
+	RUN_ADOPT  string = "adopt"
+	RUN_REMOVE string = "remove"
+	RUN_PRUNE  string = "prune"
+)
+
+// Kinds of actions done by adopt and remove
+const (
+	ActionAdopt          ActionKind = "adopt"
+	ActionUnlink         ActionKind = "unlink"
+	ActionRestore        ActionKind = "restore"
+	ActionUpdateSyncFile ActionKind = "update-sync-file"
+)
+
+var (
+	ErrRunNotFound     = errors.New("no such run in history")
+	ErrRunUndone       = errors.New("run was already undone")
+	ErrUndoConflict    = errors.New("later run changed the same paths, undo it first")
+	ErrPathChanged     = errors.New("path was changed after the run, use force to undo the rest")
+	ErrSyncFileChanged = errors.New("sync file was changed after the run, use force to undo anyway")
+)
+
+// RunAction is a single action done by recorded run
+type RunAction struct {
+	Kind        ActionKind `json:"kind" yaml:"kind"`
+	Source      string     `json:"source,omitempty" yaml:"source,omitempty"`
+	Destination string     `json:"destination,omitempty" yaml:"destination,omitempty"`
+	Reason      string     `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Run is finished sync, adopt or remove kept in history together with
+// journal of its changes and files it moved out of the way
+type Run struct {
+	ID       string    `json:"id" yaml:"id"`
+	Command  string    `json:"command" yaml:"command"`
+	Started  time.Time `json:"started" yaml:"started"`
+	Finished time.Time `json:"finished" yaml:"finished"`
+	// Commit of repo at the time of the run, empty when repo is not a git repo
+	Commit  string          `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Actions []RunAction     `json:"actions" yaml:"actions"`
+	Records []JournalRecord `json:"-" yaml:"records"`
+	// Checksums of copied destinations before the run, empty for ones without checksum
+	Checksums map[string]string `json:"-" yaml:"checksums,omitempty"`
+	// Links created by ftuck as recorded in state before the run, empty for ones without record
+	Links map[string]string `json:"-" yaml:"links,omitempty"`
+	// Result are checksums of changed paths after the run, empty for paths the run removed
+	Result map[string]string `json:"-" yaml:"result,omitempty"`
+	// SyncFileChecksum is checksum of sync file after the run
+	SyncFileChecksum string     `json:"-" yaml:"sync_file_checksum,omitempty"`
+	Undone           *time.Time `json:"undone,omitempty" yaml:"undone,omitempty"`
+
+	dir      string
+	syncFile string
+}
+
+func historyDir(stateDir string) string {
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, HISTORY_DIR_NAME)
+}
+
+// repoCommit returns commit checked out in repo, empty when it cannot be found
+func repoCommit(repoDir string) string {
+	out, err := exec.Command("git", "-C", repoDir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// finish closes journal and keeps it in history as run. Runs which did not change anything
+// and runs without state dir are not recorded.
+func (j *Journal) finish(run Run) error {
+	if j.dir == "" || len(j.Records) == 0 {
+		return j.close()
+	}
+
+	hist := historyDir(filepath.Dir(j.dir))
+	run.ID = j.Started.Format("20060102-150405")
+	run.dir = filepath.Join(hist, run.ID)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(run.dir); os.IsNotExist(err) {
+			break
+		}
+		run.ID = j.Started.Format("20060102-150405") + "-" + strconv.Itoa(i)
+		run.dir = filepath.Join(hist, run.ID)
+	}
+	err := os.MkdirAll(run.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// files moved out of the way are needed to undo the run
+	if _, err := os.Stat(j.stash); err == nil {
+		runStash := filepath.Join(run.dir, JOURNAL_STASH_DIR)
+		err = movePath(j.stash, runStash)
+		if err != nil {
+			return err
+		}
+		for i, rec := range j.Records {
+			if rec.Moved != "" && isWithin(j.stash, rec.Moved) {
+				rel, _ := filepath.Rel(j.stash, rec.Moved)
+				j.Records[i].Moved = filepath.Join(runStash, rel)
+			}
+		}
+		j.stash = runStash
+		err = j.save()
+		if err != nil {
+			return err
+		}
+	}
+
+	run.Started = j.Started
+	run.Finished = time.Now()
+	run.Records = j.Records
+	run.Actions = j.actions
+	run.Result = map[string]string{}
+	for _, rec := range j.Records {
+		if rec.Op != OP_CHMOD && rec.Op != OP_CHOWN && rec.Op != OP_MKDIR {
+			run.Result[rec.Path] = leftBehind(rec.Path)
+		}
+	}
+	run.SyncFileChecksum = leftBehind(run.syncFile)
+	err = run.save()
+	if err != nil {
+		return err
+	}
+	slog.Info("run recorded in history", "id", run.ID, "changes", len(run.Records))
+	return errors.Join(os.RemoveAll(j.dir), pruneHistory(hist))
+}
+
+func (run *Run) save() error {
+	d, err := yaml.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(run.dir, RUN_FILE_NAME), d, 0600)
+}
+
+// pruneHistory removes oldest runs over HISTORY_LIMIT
+func pruneHistory(hist string) error {
+	runs, err := listRuns(hist)
+	if err != nil || len(runs) <= HISTORY_LIMIT {
+		return err
+	}
+	var errs error
+	for _, run := range runs[HISTORY_LIMIT:] {
+		errs = errors.Join(errs, os.RemoveAll(run.dir))
+	}
+	return errs
+}
+
+// ListRuns returns runs recorded in history from the newest one
+func ListRuns(conf syncFileGetter) ([]Run, error) {
+	return listRuns(historyDir(stateDir(conf)))
+}
+
+func listRuns(hist string) ([]Run, error) {
+	if hist == "" {
+		return []Run{}, nil
+	}
+	entries, err := os.ReadDir(hist)
+	if err != nil && os.IsNotExist(err) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []Run{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		run, err := loadRun(filepath.Join(hist, e.Name()))
+		if err != nil {
+			slog.Warn("skipping unreadable run", "id", e.Name(), "error", err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	slices.SortFunc(runs, func(a, b Run) int {
+		return b.Started.Compare(a.Started)
+	})
+	return runs, nil
+}
+
+func loadRun(dir string) (Run, error) {
+	d, err := os.ReadFile(filepath.Join(dir, RUN_FILE_NAME))
+	if err != nil {
+		return Run{}, err
+	}
+	run := Run{}
+	err = yaml.Unmarshal(d, &run)
+	if err != nil {
+		return Run{}, err
+	}
+	run.dir = dir
+	return run, nil
+}
+
+// leftBehind returns checksum of path, empty when it does not exist
+func leftBehind(p string) string {
+	if p == "" {
+		return ""
+	}
+	sum, err := Checksum(p)
+	if err != nil {
+		return ""
+	}
+	return sum
+}
+
+// changed tells if path is no longer what run left behind. Directories created
+// by run are not checked, they are removed only when empty.
+func (run Run) changed(rec JournalRecord) bool {
+	if rec.Op == OP_CHMOD || rec.Op == OP_CHOWN || rec.Op == OP_MKDIR {
+		return false
+	}
+	return leftBehind(rec.Path) != run.Result[rec.Path]
+}
+
+// touches tells if run changed path, its parent or anything inside it
+func (run Run) touches(p string) bool {
+	for _, rec := range run.Records {
+		if isWithin(rec.Path, p) || isWithin(p, rec.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// UndoRun reverts changes of run with given id, empty id undoes the newest run
+// which was not undone yet. Runs after it which changed the same paths must be undone first.
+// Undo is refused when sync file or paths changed by the run were changed since,
+// with force changed paths are left as they are and the rest is undone.
+func UndoRun(conf syncFileGetter, id string, force bool) (Run, error) {
+	dir := stateDir(conf)
+	j, err := LoadJournal(dir)
+	if err != nil {
+		return Run{}, err
+	}
+	if j != nil {
+		return Run{}, fmt.Errorf("(journal = %s) %w", j.dir, ErrUnfinishedSync)
+	}
+
+	runs, err := ListRuns(conf)
+	if err != nil {
+		return Run{}, err
+	}
+	i := slices.IndexFunc(runs, func(run Run) bool {
+		if id == "" {
+			return run.Undone == nil
+		}
+		return run.ID == id
+	})
+	if i < 0 {
+		return Run{}, fmt.Errorf("(run = %s) %w", id, ErrRunNotFound)
+	}
+	run := runs[i]
+	if run.Undone != nil {
+		return run, fmt.Errorf("(run = %s) %w", run.ID, ErrRunUndone)
+	}
+	for _, later := range runs[:i] {
+		if later.Undone != nil {
+			continue
+		}
+		for _, rec := range run.Records {
+			if later.touches(rec.Path) {
+				return run, fmt.Errorf("(run = %s, later run = %s, path = %s) %w", run.ID, later.ID, rec.Path, ErrUndoConflict)
+			}
+		}
+	}
+
+	if !force && leftBehind(syncFilePath(conf)) != run.SyncFileChecksum {
+		return run, fmt.Errorf("(run = %s) %w", run.ID, ErrSyncFileChanged)
+	}
+	changed := map[string]bool{}
+	for _, rec := range run.Records {
+		if run.changed(rec) {
+			changed[rec.Path] = true
+		}
+	}
+	if len(changed) > 0 && !force {
+		paths := slices.Sorted(maps.Keys(changed))
+		return run, fmt.Errorf("(run = %s, paths = %s) %w", run.ID, strings.Join(paths, ", "), ErrPathChanged)
+	}
+
+	slog.Info("undoing run", "id", run.ID, "command", run.Command, "changes", len(run.Records))
+	var errs error
+	for _, rec := range slices.Backward(run.Records) {
+		if changed[rec.Path] {
+			slog.Warn("not undoing, path was changed after the run", "op", rec.Op, "path", rec.Path)
+			continue
+		}
+		err := undo(rec)
+		if err != nil {
+			slog.Error("undoing", "op", rec.Op, "path", rec.Path, "error", err)
+			errs = errors.Join(errs, err)
+		}
+	}
+	if errs != nil {
+		// every step can be repeated so undo can be run again
+		return run, errs
+	}
+
+	if len(run.Checksums) > 0 || len(run.Links) > 0 {
+		st, err := LoadState(dir)
+		if err != nil {
+			return run, err
+		}
+		for dest, sum := range run.Checksums {
+			if sum == "" {
+				st.DeleteChecksum(dest)
+			} else {
+				st.SetChecksum(dest, sum)
+			}
+		}
+		for dest, target := range run.Links {
+			if target == "" {
+				st.DeleteLink(dest)
+			} else {
+				st.SetLink(dest, target)
+			}
+		}
+		err = st.Save()
+		if err != nil {
+			return run, err
+		}
+	}
+
+	now := time.Now()
+	run.Undone = &now
+	err = run.save()
+	if err != nil {
+		return run, err
+	}
+	if len(changed) > 0 {
+		// files moved aside for changed paths are not lost
+		slog.Warn("files moved aside by the run are kept", "path", filepath.Join(run.dir, JOURNAL_STASH_DIR))
+		return run, nil
+	}
+	return run, os.RemoveAll(filepath.Join(run.dir, JOURNAL_STASH_DIR))
+}
@@ -0,0 +1,200 @@
+package filesync
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestUndoRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	stateDir := path.Join(tmpDir, "state")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "bashrc"), []byte("bashrc"), 0644)
+	_ = os.WriteFile(path.Join(repo, "token"), []byte("token"), 0644)
+	_ = os.WriteFile(path.Join(home, ".bashrc"), []byte("old"), 0644)
+	_ = os.WriteFile(path.Join(home, ".vimrc"), []byte("vimrc"), 0644)
+	t.Setenv("HOME", home)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, stateDir}
+	s := &Schema{
+		Entries: []SyncDefinition{
+			{Source: "bashrc", Destination: ".bashrc"},
+			{Source: "token", Destination: ".config/token", Mode: MODE_COPY},
+		},
+	}
+
+	p, err := s.Plan(conf, PlanOptions{BackupDir: path.Join(tmpDir, "backups")})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	// nothing to do, not recorded
+	p, _ = s.Plan(conf, PlanOptions{})
+	if err := p.Execute(); err != nil {
+		t.Fatalf("second Execute() failed: %v", err)
+	}
+	if _, err := Adopt(conf, path.Join(home, ".vimrc")); err != nil {
+		t.Fatalf("Adopt() failed: %v", err)
+	}
+
+	runs, err := ListRuns(conf)
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("ListRuns() = %v, error %v, want 2 runs", runs, err)
+	}
+	if runs[0].Command != RUN_ADOPT || runs[1].Command != RUN_SYNC || len(runs[1].Actions) != 2 {
+		t.Errorf("ListRuns() = %+v, want adopt after sync with 2 actions", runs)
+	}
+	if _, err := os.Stat(path.Join(stateDir, JOURNAL_DIR_NAME)); !os.IsNotExist(err) {
+		t.Errorf("journal of finished run was kept")
+	}
+
+	// adopt changed the sync file after sync created it
+	s.Entries = append(s.Entries, SyncDefinition{Source: "bashrc", Destination: ".profile"})
+	p, _ = s.Plan(conf, PlanOptions{})
+	_ = p.Execute()
+	if err := os.WriteFile(path.Join(home, ".profile.bak"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UndoRun(conf, runs[1].ID, false); !errors.Is(err, ErrSyncFileChanged) {
+		t.Fatalf("UndoRun(sync) error = %v, want %v", err, ErrSyncFileChanged)
+	}
+	if _, err := UndoRun(conf, runs[1].ID, true); err != nil {
+		t.Fatalf("UndoRun(sync) with force failed: %v", err)
+	}
+	if d, _ := os.ReadFile(path.Join(home, ".bashrc")); string(d) != "old" {
+		t.Errorf("backed up file was not restored, content %q", d)
+	}
+	if _, err := os.Lstat(path.Join(home, ".config")); !os.IsNotExist(err) {
+		t.Errorf("created directories were not removed")
+	}
+	st, _ := LoadState(stateDir)
+	if st.Checksums[path.Join(home, ".config/token")] != "" {
+		t.Errorf("checksum of undone copy was kept")
+	}
+	if _, ok := st.Links[path.Join(home, ".bashrc")]; ok {
+		t.Errorf("record of undone link was kept")
+	}
+	if _, err := os.Lstat(path.Join(home, ".profile")); err != nil {
+		t.Errorf("link of later run was removed: %v", err)
+	}
+	if _, err := UndoRun(conf, runs[1].ID, false); !errors.Is(err, ErrRunUndone) {
+		t.Errorf("second UndoRun() error = %v, want %v", err, ErrRunUndone)
+	}
+
+	// newest not undone run
+	run, err := UndoRun(conf, "", false)
+	if err != nil || len(run.Actions) != 1 {
+		t.Fatalf("UndoRun() = %+v, error %v", run, err)
+	}
+	if _, err := os.Lstat(path.Join(home, ".profile")); !os.IsNotExist(err) {
+		t.Errorf("link was not removed by undo")
+	}
+	run, err = UndoRun(conf, "", false)
+	if err != nil || run.Command != RUN_ADOPT {
+		t.Fatalf("UndoRun() = %+v, error %v, want adopt", run, err)
+	}
+	if fi, _ := os.Lstat(path.Join(home, ".vimrc")); fi == nil || !fi.Mode().IsRegular() {
+		t.Errorf("adopted file was not moved back")
+	}
+	if _, err := os.Stat(path.Join(repo, ".vimrc")); !os.IsNotExist(err) {
+		t.Errorf("adopted file was left in repo")
+	}
+	if st, _ := LoadState(stateDir); len(st.Links) != 0 {
+		t.Errorf("state links after undo = %v, want none", st.Links)
+	}
+	if _, err := os.Stat(path.Join(repo, SYNC_FILE_NAME)); !os.IsNotExist(err) {
+		t.Errorf("sync file created by adopt was not removed")
+	}
+	if _, err := UndoRun(conf, "", false); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("UndoRun() with nothing left error = %v, want %v", err, ErrRunNotFound)
+	}
+}
+
+func TestUndoRun_Conflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(path.Join(home, ".config"), 0755)
+	_ = os.WriteFile(path.Join(home, ".config", "a"), []byte("a"), 0644)
+	_ = os.WriteFile(path.Join(home, ".config", "b"), []byte("b"), 0644)
+	t.Setenv("HOME", home)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+
+	for _, f := range []string{"a", "b"} {
+		if _, err := Adopt(conf, path.Join(home, ".config", f)); err != nil {
+			t.Fatalf("Adopt() failed: %v", err)
+		}
+	}
+	runs, _ := ListRuns(conf)
+	if len(runs) != 2 {
+		t.Fatalf("ListRuns() = %v, want 2 runs", runs)
+	}
+	// both runs changed the sync file
+	if _, err := UndoRun(conf, runs[1].ID, false); !errors.Is(err, ErrUndoConflict) {
+		t.Errorf("UndoRun() error = %v, want %v", err, ErrUndoConflict)
+	}
+	if fi, _ := os.Lstat(path.Join(home, ".config", "a")); fi == nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("conflicting undo changed files")
+	}
+}
+
+func TestUndoRun_Changed(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "app.conf"), []byte("app"), 0644)
+	_ = os.WriteFile(path.Join(repo, "token"), []byte("token"), 0644)
+	t.Setenv("HOME", home)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+	s := &Schema{
+		Entries: []SyncDefinition{
+			{Source: "app.conf", Destination: ".config/app/app.conf"},
+			{Source: "token", Destination: ".token", Mode: MODE_COPY},
+		},
+	}
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	runs, _ := ListRuns(conf)
+	if len(runs) != 1 {
+		t.Fatalf("ListRuns() = %v, want 1 run", runs)
+	}
+
+	// files written after the run
+	notes := path.Join(home, ".config", "app", "notes")
+	_ = os.WriteFile(notes, []byte("notes"), 0644)
+	_ = os.WriteFile(path.Join(home, ".token"), []byte("edited"), 0644)
+
+	if _, err := UndoRun(conf, runs[0].ID, false); !errors.Is(err, ErrPathChanged) {
+		t.Fatalf("UndoRun() error = %v, want %v", err, ErrPathChanged)
+	}
+	if _, err := os.Lstat(path.Join(home, ".config", "app", "app.conf")); err != nil {
+		t.Errorf("refused undo changed files: %v", err)
+	}
+
+	if _, err := UndoRun(conf, runs[0].ID, true); err != nil {
+		t.Fatalf("UndoRun() with force failed: %v", err)
+	}
+	if _, err := os.Lstat(path.Join(home, ".config", "app", "app.conf")); !os.IsNotExist(err) {
+		t.Errorf("link in created directory was not removed")
+	}
+	if d, _ := os.ReadFile(notes); string(d) != "notes" {
+		t.Errorf("file put in created directory was removed")
+	}
+	if d, _ := os.ReadFile(path.Join(home, ".token")); string(d) != "edited" {
+		t.Errorf("copy changed after the run = %q, want it kept", d)
+	}
+}
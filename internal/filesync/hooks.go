@@ -0,0 +1,146 @@
+package filesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DEFAULT_HOOK_TIMEOUT limits every hook when sync file does not set other
+const DEFAULT_HOOK_TIMEOUT time.Duration = 30 * time.Second
+
+// Triggers of hooks
+const (
+	HOOK_BEFORE    string = "before"
+	HOOK_AFTER     string = "after"
+	HOOK_ON_CHANGE string = "on_change"
+)
+
+// HOOK_ENV are variables passed to hooks from ftuck environment, everything else is dropped
+var HOOK_ENV = []string{"HOME", "PATH", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "TERM", "TMPDIR", "DISPLAY", "WAYLAND_DISPLAY", "DBUS_SESSION_BUS_ADDRESS"}
+
+var ErrHookFailed = errors.New("hook failed")
+
+// Hooks are shell commands run around sync. Hooks of sync file run once per sync,
+// hooks of entry run around its actions unless entry is skipped on this machine.
+type Hooks struct {
+	Before []string `yaml:"before,omitempty"`
+	After  []string `yaml:"after,omitempty"`
+	// OnChange runs after actions only when entry link or content was changed,
+	// sync file wide ones when any entry changed
+	OnChange []string `yaml:"on_change,omitempty"`
+}
+
+// hook is a single planned command
+type hook struct {
+	trigger string
+	command string
+	dir     string
+	env     []string
+	timeout time.Duration
+}
+
+// hookTimeout returns timeout from settings. Invalid values are reported by validate
+// and fall back to DEFAULT_HOOK_TIMEOUT.
+func (s *Schema) hookTimeout() time.Duration {
+	d, err := time.ParseDuration(s.Settings.HookTimeout)
+	if err != nil || d <= 0 {
+		return DEFAULT_HOOK_TIMEOUT
+	}
+	return d
+}
+
+// hookEnv returns environment of hooks. Entry hooks get paths of their entry.
+func hookEnv(repoDir string, re ResolvedEntry) []string {
+	env := []string{}
+	for _, k := range HOOK_ENV {
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "XDG_") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, "FTUCK_REPO="+repoDir)
+	if re.Destination != "" {
+		env = append(env, "FTUCK_SOURCE="+re.Source, "FTUCK_DEST="+re.Destination)
+	}
+	return env
+}
+
+// hookActions plans commands of given trigger
+func (s *Schema) hookActions(r *Resolver, h *Hooks, trigger string, group int, re ResolvedEntry) []Action {
+	if h == nil {
+		return nil
+	}
+	commands := h.Before
+	switch trigger {
+	case HOOK_AFTER:
+		commands = h.After
+	case HOOK_ON_CHANGE:
+		commands = h.OnChange
+	}
+
+	res := []Action{}
+	for _, c := range commands {
+		res = append(res, Action{
+			Kind:        ActionHook,
+			Source:      re.Source,
+			Destination: re.Destination,
+			Reason:      fmt.Sprintf("%s: %s", trigger, c),
+			group:       group,
+			hook: &hook{
+				trigger: trigger,
+				command: c,
+				dir:     r.RepoDir,
+				env:     hookEnv(r.RepoDir, re),
+				timeout: s.hookTimeout(),
+			},
+		})
+	}
+	return res
+}
+
+// run executes hook with sh in repo dir and logs its output
+func (h *hook) run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Dir = h.dir
+	cmd.Env = h.env
+	// do not wait forever for background processes keeping output open
+	cmd.WaitDelay = time.Second
+
+	slog.Info("running hook", "trigger", h.trigger, "command", h.command)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+	if err != nil {
+		slog.Error("hook failed", "command", h.command, "error", err, "output", output)
+		return fmt.Errorf("(hook = %s) %w: %w", h.command, ErrHookFailed, err)
+	}
+	if output != "" {
+		slog.Info("hook output", "command", h.command, "output", output)
+	}
+	return nil
+}
+
+// changes tells if executing action of this kind changes destination
+func (k ActionKind) changes() bool {
+	switch k {
+	case ActionSkip, ActionConflict, ActionHook, ActionFailed, ActionForget:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,90 @@
+package filesync
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestSchema_ExecuteHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "tmux.conf"), []byte("set -g mouse on"), 0644)
+	_ = os.WriteFile(path.Join(repo, "fonts"), []byte("font"), 0644)
+	log := path.Join(tmpDir, "hooks.log")
+	t.Setenv("FTUCK_TEST_SECRET", "leaked")
+	conf := &confMock{path.Join(repo, SYNC_FILE_NAME)}
+	record := func(name string) string {
+		return "echo " + name + " >> " + log
+	}
+	s := &Schema{
+		Hooks: Hooks{
+			Before:   []string{record("sync-before")},
+			After:    []string{record("sync-after")},
+			OnChange: []string{record("sync-changed")},
+		},
+		Entries: []SyncDefinition{
+			{Source: "tmux.conf", Destination: path.Join(home, ".tmux.conf"), Hooks: &Hooks{
+				Before:   []string{record("tmux-before")},
+				After:    []string{record("tmux-after")},
+				OnChange: []string{record("tmux-changed $(basename $FTUCK_DEST) $(basename $PWD) ${FTUCK_TEST_SECRET:-clean}")},
+			}},
+			{Source: "fonts", Destination: path.Join(home, "fonts"), Hooks: &Hooks{
+				OnChange: []string{record("fonts-changed")},
+			}},
+			{Source: "fonts", Destination: path.Join(home, "skipped"), When: &Condition{OS: []string{"plan9"}}, Hooks: &Hooks{
+				Before: []string{record("skipped-before")},
+			}},
+		},
+	}
+	_ = os.Symlink(path.Join(repo, "fonts"), path.Join(home, "fonts"))
+
+	sync := func(want string) {
+		t.Helper()
+		_ = os.Remove(log)
+		p, err := s.Plan(conf, PlanOptions{})
+		if err != nil {
+			t.Fatalf("Plan() failed: %v", err)
+		}
+		if err := p.Execute(); err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		got, _ := os.ReadFile(log)
+		if strings.TrimSpace(string(got)) != want {
+			t.Errorf("hooks run:\n%s\nwant:\n%s", got, want)
+		}
+	}
+
+	sync("sync-before\ntmux-before\ntmux-after\ntmux-changed .tmux.conf repo clean\nsync-changed\nsync-after")
+	sync("sync-before\ntmux-before\ntmux-after\nsync-after")
+
+	s.Settings.HookTimeout = "100ms"
+	s.Hooks = Hooks{Before: []string{"sleep 5"}}
+	p, _ := s.Plan(conf, PlanOptions{})
+	if err := p.Execute(); !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Execute() error = %v, want %v", err, ErrHookFailed)
+	}
+
+	s.Hooks = Hooks{Before: []string{"exit 3"}}
+	_ = os.Remove(path.Join(home, ".tmux.conf"))
+	p, _ = s.Plan(conf, PlanOptions{})
+	if err := p.Execute(); !errors.Is(err, ErrHookFailed) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrHookFailed)
+	}
+	if _, err := os.Lstat(path.Join(home, ".tmux.conf")); !os.IsNotExist(err) {
+		t.Errorf("entry was synced after failed before hook")
+	}
+
+	// plan with hooks only is journaled in state dir like any other
+	stateDir := path.Join(tmpDir, "state")
+	hooksOnly := &Schema{Hooks: Hooks{Before: []string{"test -d " + path.Join(stateDir, JOURNAL_DIR_NAME)}}}
+	p, _ = hooksOnly.Plan(&confStateMock{*conf, stateDir}, PlanOptions{})
+	if err := p.Execute(); err != nil {
+		t.Errorf("hook did not find journal in state dir: %v", err)
+	}
+}
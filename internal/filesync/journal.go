@@ -0,0 +1,381 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JOURNAL_DIR_NAME is directory in state dir where running sync keeps its journal
+// and files it moved out of the way
+const JOURNAL_DIR_NAME string = "journal"
+
+const (
+	JOURNAL_FILE_NAME string = "journal.yaml"
+	JOURNAL_STASH_DIR string = "stash"
+)
+
+// Operations recorded in journal
+const (
+	// OP_CREATED path did not exist before sync
+	OP_CREATED string = "created"
+	// OP_MKDIR path was created as empty directory, its content is recorded separately
+	OP_MKDIR string = "mkdir"
+	// OP_MOVED path was moved out of the way
+	OP_MOVED string = "moved"
+	// OP_CHMOD path had different permissions
+	OP_CHMOD string = "chmod"
+	// OP_CHOWN path had different owner or group
+	OP_CHOWN string = "chown"
+	// OP_COPIED path was copied aside before being rewritten in place
+	OP_COPIED string = "copied"
+)
+
+var ErrUnfinishedSync = errors.New("previous sync was interrupted, run recover first")
+
+// JournalRecord is a single filesystem change made by sync
+type JournalRecord struct {
+	Op   string `yaml:"op"`
+	Path string `yaml:"path"`
+	// Moved is where path was moved or copied
+	Moved string `yaml:"moved,omitempty"`
+	// Perm are octal permissions path had before chmod
+	Perm string `yaml:"perm,omitempty"`
+	// Owner is uid:gid path had before chown
+	Owner string `yaml:"owner,omitempty"`
+}
+
+// Journal records every change of running sync before it is made,
+// so sync that failed or was interrupted can be rolled back
+type Journal struct {
+	Started time.Time       `yaml:"started"`
+	Records []JournalRecord `yaml:"records"`
+
+	// dir is empty for journal which is not persisted
+	dir   string
+	stash string
+	// actions done so far, kept in history when journal is finished
+	actions []RunAction
+}
+
+func journalDir(stateDir string) string {
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, JOURNAL_DIR_NAME)
+}
+
+// beginJournal starts journal of new sync, empty state dir gives in memory journal
+func beginJournal(stateDir string) (*Journal, error) {
+	j := &Journal{
+		Started: time.Now(),
+		Records: []JournalRecord{},
+		dir:     journalDir(stateDir),
+	}
+	if j.dir == "" {
+		return j, nil
+	}
+	if _, err := os.Stat(filepath.Join(j.dir, JOURNAL_FILE_NAME)); err == nil {
+		return nil, fmt.Errorf("(journal = %s) %w", j.dir, ErrUnfinishedSync)
+	}
+	j.stash = filepath.Join(j.dir, JOURNAL_STASH_DIR)
+	return j, j.save()
+}
+
+// LoadJournal reads journal left by interrupted sync, nil when there is none
+func LoadJournal(stateDir string) (*Journal, error) {
+	dir := journalDir(stateDir)
+	if dir == "" {
+		return nil, nil
+	}
+	d, err := os.ReadFile(filepath.Join(dir, JOURNAL_FILE_NAME))
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{dir: dir, stash: filepath.Join(dir, JOURNAL_STASH_DIR)}
+	err = yaml.Unmarshal(d, j)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) save() error {
+	if j.dir == "" {
+		return nil
+	}
+	d, err := yaml.Marshal(j)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(j.dir, 0700)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(j.dir, JOURNAL_FILE_NAME), d, 0600)
+}
+
+// add persists record before the change it describes is made
+func (j *Journal) add(rec JournalRecord) error {
+	j.Records = append(j.Records, rec)
+	return j.save()
+}
+
+// create records path as created by sync and creates it
+func (j *Journal) create(p string, create func() error) error {
+	_, err := os.Lstat(p)
+	if err == nil || !os.IsNotExist(err) {
+		// creating will fail and existing path must survive rollback
+		return create()
+	}
+	err = j.add(JournalRecord{Op: OP_CREATED, Path: p})
+	if err != nil {
+		return err
+	}
+	return create()
+}
+
+// move moves src to dst, rollback moves it back
+func (j *Journal) move(src, dst string) error {
+	err := j.add(JournalRecord{Op: OP_MOVED, Path: src, Moved: dst})
+	if err != nil {
+		return err
+	}
+	return movePath(src, dst)
+}
+
+// stashPath returns unused path in journal stash
+func (j *Journal) stashPath() (string, error) {
+	if j.stash == "" {
+		dir, err := os.MkdirTemp("", "ftuck-journal-")
+		if err != nil {
+			return "", err
+		}
+		j.stash = dir
+	}
+	return filepath.Join(j.stash, strconv.Itoa(len(j.Records))), nil
+}
+
+// remove moves p into journal stash so it can be put back on rollback
+func (j *Journal) remove(p string) error {
+	stashed, err := j.stashPath()
+	if err != nil {
+		return err
+	}
+	return j.move(p, stashed)
+}
+
+// preserve copies p into journal stash before caller rewrites it in place.
+// Missing p is recorded as created by the caller.
+func (j *Journal) preserve(p string) error {
+	_, err := os.Lstat(p)
+	if err != nil && os.IsNotExist(err) {
+		return j.add(JournalRecord{Op: OP_CREATED, Path: p})
+	}
+	if err != nil {
+		return err
+	}
+	stashed, err := j.stashPath()
+	if err != nil {
+		return err
+	}
+	err = j.add(JournalRecord{Op: OP_COPIED, Path: p, Moved: stashed})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(stashed), 0700)
+	if err != nil {
+		return err
+	}
+	return copyPath(p, stashed)
+}
+
+// done records action which was applied
+func (j *Journal) done(a Action) {
+	j.actions = append(j.actions, RunAction{Kind: a.Kind, Source: a.Source, Destination: a.Destination, Reason: a.Reason})
+}
+
+func (j *Journal) chmod(p string, perm os.FileMode) error {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	err = j.add(JournalRecord{Op: OP_CHMOD, Path: p, Perm: fmt.Sprintf("%04o", fi.Mode().Perm())})
+	if err != nil {
+		return err
+	}
+	return os.Chmod(p, perm)
+}
+
+// chown changes owner and group of p, -1 keeps the current one
+func (j *Journal) chown(p string, uid, gid int) error {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	curUID, curGID, ok := fileOwner(fi)
+	if !ok {
+		return fmt.Errorf("(path = %s) %w", p, errors.ErrUnsupported)
+	}
+	err = j.add(JournalRecord{Op: OP_CHOWN, Path: p, Owner: fmt.Sprintf("%d:%d", curUID, curGID)})
+	if err != nil {
+		return err
+	}
+	return os.Chown(p, uid, gid)
+}
+
+// mkdirAll creates dir and its missing parents with given permissions regardless of umask.
+// Zero perm means DEFAULT_DIR_PERM.
+func (j *Journal) mkdirAll(dir string, perm os.FileMode) error {
+	if perm == 0 {
+		perm = DEFAULT_DIR_PERM
+	}
+	missing, err := missingDirs(dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range missing {
+		err := j.mkdir(d, perm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mkdir creates empty directory with given permissions regardless of umask,
+// rollback removes it only when it is empty
+func (j *Journal) mkdir(dir string, perm os.FileMode) error {
+	err := j.add(JournalRecord{Op: OP_MKDIR, Path: dir})
+	if err != nil {
+		return err
+	}
+	err = os.Mkdir(dir, perm)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(dir, perm)
+}
+
+// Rollback undoes recorded changes from the newest one. Every step can be repeated
+// so journal is kept when rollback fails and recover can try again.
+func (j *Journal) Rollback() error {
+	var errs error
+	for _, rec := range slices.Backward(j.Records) {
+		err := undo(rec)
+		if err != nil {
+			slog.Error("rolling back", "op", rec.Op, "path", rec.Path, "error", err)
+			errs = errors.Join(errs, err)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return j.close()
+}
+
+// journalMark is position in journal changes can be rolled back to
+type journalMark struct {
+	records int
+	actions int
+}
+
+func (j *Journal) mark() journalMark {
+	return journalMark{len(j.Records), len(j.actions)}
+}
+
+// rollbackTo undoes changes recorded after mark and keeps the earlier ones
+func (j *Journal) rollbackTo(mark journalMark) error {
+	var errs error
+	for _, rec := range slices.Backward(j.Records[mark.records:]) {
+		errs = errors.Join(errs, undo(rec))
+	}
+	if errs != nil {
+		return errs
+	}
+	j.Records = j.Records[:mark.records]
+	j.actions = j.actions[:mark.actions]
+	return j.save()
+}
+
+func undo(rec JournalRecord) error {
+	switch rec.Op {
+	case OP_CREATED:
+		slog.Info("rollback: removing", "path", rec.Path)
+		return os.RemoveAll(rec.Path)
+	case OP_MKDIR:
+		err := os.Remove(rec.Path)
+		if err != nil && !os.IsNotExist(err) {
+			// something was put inside since, it is kept together with the directory
+			slog.Warn("rollback: not removing directory", "path", rec.Path, "error", err)
+		}
+		return nil
+	case OP_MOVED, OP_COPIED:
+		if _, err := os.Lstat(rec.Moved); err != nil {
+			// move or copy did not happen
+			return nil
+		}
+		slog.Info("rollback: moving back", "path", rec.Path, "from", rec.Moved)
+		// only file or empty directory is replaced, anything else was not made by sync
+		err := os.Remove(rec.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return movePath(rec.Moved, rec.Path)
+	case OP_CHMOD:
+		perm, err := ParsePerm(rec.Perm)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(rec.Path); err != nil {
+			return nil
+		}
+		slog.Info("rollback: restoring permissions", "path", rec.Path, "perm", rec.Perm)
+		return os.Chmod(rec.Path, perm)
+	case OP_CHOWN:
+		var uid, gid int
+		_, err := fmt.Sscanf(rec.Owner, "%d:%d", &uid, &gid)
+		if err != nil {
+			return fmt.Errorf("(owner = %s) %w", rec.Owner, err)
+		}
+		if _, err := os.Lstat(rec.Path); err != nil {
+			return nil
+		}
+		slog.Info("rollback: restoring owner", "path", rec.Path, "owner", rec.Owner)
+		return os.Chown(rec.Path, uid, gid)
+	default:
+		return fmt.Errorf("unknown journal operation %s", rec.Op)
+	}
+}
+
+// close removes journal together with files moved out of the way
+func (j *Journal) close() error {
+	if j.dir != "" {
+		return os.RemoveAll(j.dir)
+	}
+	if j.stash != "" {
+		return os.RemoveAll(j.stash)
+	}
+	return nil
+}
+
+// RecoverSync rolls back sync interrupted before it finished. False is returned when
+// there was nothing to recover.
+func RecoverSync(conf syncFileGetter) (bool, error) {
+	j, err := LoadJournal(stateDir(conf))
+	if err != nil || j == nil {
+		return false, err
+	}
+	slog.Info("recovering interrupted sync", "started", j.Started, "changes", len(j.Records))
+	return true, j.Rollback()
+}
@@ -0,0 +1,118 @@
+package filesync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestPlan_ExecuteRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	stateDir := path.Join(tmpDir, "state")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "bashrc"), []byte("bashrc"), 0644)
+	_ = os.WriteFile(path.Join(repo, "token"), []byte("token"), 0644)
+	_ = os.WriteFile(path.Join(home, "old"), []byte("old"), 0644)
+	_ = os.Symlink(path.Join(home, "old"), path.Join(home, ".bashrc"))
+	_ = os.WriteFile(path.Join(home, ".profile"), []byte("existing"), 0644)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, stateDir}
+	s := &Schema{
+		Hooks: Hooks{After: []string{"exit 1"}},
+		Entries: []SyncDefinition{
+			{Source: "bashrc", Destination: ".bashrc", Perm: "0600"},
+			{Source: "token", Destination: ".config/app/token", Mode: MODE_COPY},
+			{Source: "bashrc", Destination: ".profile"},
+		},
+	}
+	t.Setenv("HOME", home)
+
+	check := func() {
+		t.Helper()
+		if target, _ := os.Readlink(path.Join(home, ".bashrc")); target != path.Join(home, "old") {
+			t.Errorf(".bashrc links to %q after rollback", target)
+		}
+		if fi, _ := os.Stat(path.Join(repo, "bashrc")); fi.Mode().Perm() != 0644 {
+			t.Errorf("source permissions = %v after rollback", fi.Mode().Perm())
+		}
+		if _, err := os.Lstat(path.Join(home, ".config")); !os.IsNotExist(err) {
+			t.Errorf("created directories were not removed")
+		}
+		if d, _ := os.ReadFile(path.Join(home, ".profile")); string(d) != "existing" {
+			t.Errorf("backed up file was not restored, content %q", d)
+		}
+		if _, err := os.Stat(path.Join(stateDir, JOURNAL_DIR_NAME)); !os.IsNotExist(err) {
+			t.Errorf("journal was not removed")
+		}
+		if _, err := os.Stat(path.Join(stateDir, STATE_FILE_NAME)); !os.IsNotExist(err) {
+			t.Errorf("state of rolled back sync was saved")
+		}
+	}
+
+	p, err := s.Plan(conf, PlanOptions{BackupDir: path.Join(tmpDir, "backups")})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); !errors.Is(err, ErrHookFailed) {
+		t.Fatalf("Execute() error = %v, want %v", err, ErrHookFailed)
+	}
+	check()
+	if sets, _ := ListBackups(path.Join(tmpDir, "backups")); len(sets) != 0 {
+		t.Errorf("backup set of rolled back sync was kept: %v", sets)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Hooks = Hooks{}
+	p, _ = s.Plan(conf, PlanOptions{})
+	if err := p.ExecuteContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteContext() error = %v, want %v", err, context.Canceled)
+	}
+	check()
+}
+
+func TestRecoverSync(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateDir := path.Join(tmpDir, "state")
+	conf := &confStateMock{confMock{path.Join(tmpDir, SYNC_FILE_NAME)}, stateDir}
+	link := path.Join(tmpDir, "link")
+	_ = os.Symlink("/old/target", link)
+
+	// sync killed after replacing the link
+	j, err := beginJournal(stateDir)
+	if err != nil {
+		t.Fatalf("beginJournal() failed: %v", err)
+	}
+	if err := j.mkdirAll(path.Join(tmpDir, "a", "b"), 0700); err != nil {
+		t.Fatalf("mkdirAll() failed: %v", err)
+	}
+	if err := j.remove(link); err != nil {
+		t.Fatalf("remove() failed: %v", err)
+	}
+	_ = j.create(link, func() error { return os.Symlink("/new/target", link) })
+
+	p := Plan{Actions: []Action{{Kind: ActionSkip}}, stateDir: stateDir}
+	if err := p.Execute(); !errors.Is(err, ErrUnfinishedSync) {
+		t.Fatalf("Execute() error = %v, want %v", err, ErrUnfinishedSync)
+	}
+
+	recovered, err := RecoverSync(conf)
+	if err != nil || !recovered {
+		t.Fatalf("RecoverSync() = %v, error %v", recovered, err)
+	}
+	if target, _ := os.Readlink(link); target != "/old/target" {
+		t.Errorf("link points to %q after recover", target)
+	}
+	if _, err := os.Stat(path.Join(tmpDir, "a")); !os.IsNotExist(err) {
+		t.Errorf("created directories were not removed")
+	}
+
+	recovered, err = RecoverSync(conf)
+	if err != nil || recovered {
+		t.Errorf("second RecoverSync() = %v, error %v", recovered, err)
+	}
+}
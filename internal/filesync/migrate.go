@@ -0,0 +1,166 @@
+package filesync
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MigrateSchema rewrites sync file content to current schema version.
+// Document is transformed on yaml node level so comments are kept.
+// Returns false when content already is in current version.
+func MigrateSchema(data []byte) ([]byte, bool, error) {
+	doc := yaml.Node{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.SequenceNode, Tag: "!!seq"}},
+		}
+	}
+
+	root := doc.Content[0]
+	switch root.Kind {
+	case yaml.SequenceNode:
+		doc.Content[0] = migrateBareList(root)
+	case yaml.MappingNode:
+		changed, err := setVersion(root)
+		if err != nil || !changed {
+			return data, false, err
+		}
+	default:
+		return nil, false, fmt.Errorf("(line %d) sync file must be a list or a mapping", root.Line)
+	}
+
+	d, err := encodeDocument(&doc)
+	if err != nil {
+		return nil, false, err
+	}
+	return d, true, nil
+}
+
+func encodeDocument(doc *yaml.Node) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	err := enc.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func versionNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(SCHEMA_VERSION)}
+}
+
+func keyNode(key string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+}
+
+// migrateBareList wraps list of definitions in versioned document
+func migrateBareList(list *yaml.Node) *yaml.Node {
+	root := &yaml.Node{
+		Kind:        yaml.MappingNode,
+		Tag:         "!!map",
+		HeadComment: list.HeadComment,
+		FootComment: list.FootComment,
+		Content: []*yaml.Node{
+			keyNode("version"), versionNode(),
+			keyNode("entries"), list,
+		},
+	}
+	list.HeadComment = ""
+	list.FootComment = ""
+	return root
+}
+
+// setVersion updates version key of versioned document
+func setVersion(root *yaml.Node) (bool, error) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "version" {
+			continue
+		}
+		v, err := strconv.Atoi(root.Content[i+1].Value)
+		if err != nil {
+			return false, fmt.Errorf("(line %d) version is not a number: %w", root.Content[i+1].Line, err)
+		}
+		if v > SCHEMA_VERSION {
+			return false, fmt.Errorf("(version = %d) %w", v, ErrUnsupportedVersion)
+		}
+		if v == SCHEMA_VERSION {
+			return false, nil
+		}
+		root.Content[i+1].Value = strconv.Itoa(SCHEMA_VERSION)
+		return true, nil
+	}
+	root.Content = append([]*yaml.Node{keyNode("version"), versionNode()}, root.Content...)
+	return true, nil
+}
+
+// listValue returns list under key of mapping, missing key is added with empty list
+func listValue(root *yaml.Node, key string) *yaml.Node {
+	if v := mappingValue(root, key); v != nil {
+		return v
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	root.Content = append(root.Content, keyNode(key), list)
+	return list
+}
+
+// updateList makes list node hold items. Nodes of items which are still present are kept
+// together with their comments, nodes of removed items are dropped and new items appended.
+func updateList[T any](list *yaml.Node, items []T) error {
+	if list.Kind != yaml.SequenceNode {
+		return fmt.Errorf("(line %d) expected a list", list.Line)
+	}
+	used := make([]bool, len(items))
+	kept := []*yaml.Node{}
+	for _, n := range list.Content {
+		var item T
+		err := n.Decode(&item)
+		if err != nil {
+			return err
+		}
+		i := -1
+		for j, it := range items {
+			if !used[j] && reflect.DeepEqual(it, item) {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			continue
+		}
+		used[i] = true
+		kept = append(kept, n)
+	}
+	for i, item := range items {
+		if used[i] {
+			continue
+		}
+		n := &yaml.Node{}
+		err := n.Encode(item)
+		if err != nil {
+			return err
+		}
+		kept = append(kept, n)
+	}
+	list.Content = kept
+	if len(kept) > 0 {
+		// `entries: []` gets block style once it has items
+		list.Style &^= yaml.FlowStyle
+	}
+	return nil
+}
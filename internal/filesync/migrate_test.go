@@ -0,0 +1,175 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateSchema(t *testing.T) {
+	tests := []struct {
+		name        string // description of this test case
+		data        string
+		wantChanged bool
+		// strings expected in migrated document
+		wantContains []string
+	}{
+		{
+			name: "bare list keeps comments",
+			data: `# my dotfiles
+- src: bashrc # shell
+  dest: ~/.bashrc
+# editor
+- src: nvim
+  dest: ~/.config/nvim
+`,
+			wantChanged:  true,
+			wantContains: []string{"# my dotfiles", "version: 2", "entries:", "# shell", "# editor", "dest: ~/.config/nvim"},
+		},
+		{
+			name:         "mapping without version",
+			data:         "entries:\n  - src: a # keep\n    dest: b\n",
+			wantChanged:  true,
+			wantContains: []string{"version: 2", "# keep"},
+		},
+		{
+			name:        "current version is not changed",
+			data:        "version: 2\nentries: []\n",
+			wantChanged: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := MigrateSchema([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("MigrateSchema() failed: %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("MigrateSchema() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("migrated document does not contain %q:\n%s", want, got)
+				}
+			}
+
+			s, err := ReadSchema(got)
+			if err != nil {
+				t.Fatalf("ReadSchema() of migrated document failed: %v", err)
+			}
+			if s.Version != SCHEMA_VERSION {
+				t.Errorf("migrated version = %d, want %d", s.Version, SCHEMA_VERSION)
+			}
+		})
+	}
+
+	if _, _, err := MigrateSchema([]byte("version: 99\nentries: []\n")); err == nil {
+		t.Error("MigrateSchema() accepted newer version")
+	}
+}
+
+func TestSchema_WriteToFile(t *testing.T) {
+	v1 := "# my dotfiles\n- src: bashrc # shell\n  dest: ~/.bashrc\n- src: vimrc\n  dest: ~/.vimrc\n"
+	v2 := "version: 2\n# editor\nentries:\n  - src: vimrc # vim\n    dest: ~/.vimrc\n"
+
+	tests := []struct {
+		name   string // description of this test case
+		data   string
+		change func(s *Schema)
+		// strings expected in and missing from saved document
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			name:         "append to version 1",
+			data:         v1,
+			change:       func(s *Schema) { s.Append(SyncDefinition{Source: "zshrc", Destination: "~/.zshrc"}) },
+			wantContains: []string{"# my dotfiles", "# shell", "- src: zshrc"},
+			wantMissing:  []string{"version", "entries"},
+		},
+		{
+			name: "remove from version 1",
+			data: v1,
+			change: func(s *Schema) {
+				s.Remove(func(sd SyncDefinition) bool { return sd.Source == "vimrc" })
+			},
+			wantContains: []string{"# shell", "src: bashrc"},
+			wantMissing:  []string{"vimrc", "version"},
+		},
+		{
+			name:         "append to version 2",
+			data:         v2,
+			change:       func(s *Schema) { s.Append(SyncDefinition{Source: "zshrc", Destination: "~/.zshrc"}) },
+			wantContains: []string{"version: 2", "# editor", "# vim", "src: zshrc"},
+		},
+		{
+			name:         "package migrates version 1",
+			data:         v1,
+			change:       func(s *Schema) { s.AddPackage(Package{Name: "nvim"}) },
+			wantContains: []string{"# my dotfiles", "# shell", "version: 2", "packages:", "name: nvim"},
+		},
+		{
+			name:         "new file",
+			data:         "",
+			change:       func(s *Schema) { s.Append(SyncDefinition{Source: "zshrc", Destination: "~/.zshrc"}) },
+			wantContains: []string{"version: 2", "src: zshrc"},
+			wantMissing:  []string{"packages"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := path.Join(t.TempDir(), SYNC_FILE_NAME)
+			if tt.data != "" {
+				_ = os.WriteFile(p, []byte(tt.data), 0644)
+			}
+			s, err := ReadSchema([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(s)
+			if err := s.WriteToFile(p); err != nil {
+				t.Fatalf("WriteToFile() failed: %v", err)
+			}
+
+			got, _ := os.ReadFile(p)
+			for _, want := range tt.wantContains {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("saved document does not contain %q:\n%s", want, got)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(string(got), missing) {
+					t.Errorf("saved document contains %q:\n%s", missing, got)
+				}
+			}
+			saved, err := ReadSchema(got)
+			if err != nil {
+				t.Fatalf("ReadSchema() of saved document failed: %v", err)
+			}
+			if !reflect.DeepEqual(saved.Entries, s.Entries) || len(saved.Packages) != len(s.Packages) {
+				t.Errorf("saved schema = %+v, want %+v", saved, s)
+			}
+		})
+	}
+}
+
+func TestWriteSyncFile(t *testing.T) {
+	p := path.Join(t.TempDir(), SYNC_FILE_NAME)
+	_ = os.WriteFile(p, []byte("- src: a\n  dest: b\n"), 0600)
+
+	if err := WriteSyncFile(p, []byte("version: 2\n")); err != nil {
+		t.Fatalf("WriteSyncFile() failed: %v", err)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("sync file mode = %v, want 0600 kept", fi.Mode().Perm())
+	}
+	if d, _ := os.ReadFile(p); string(d) != "version: 2\n" {
+		t.Errorf("sync file content = %q", d)
+	}
+}
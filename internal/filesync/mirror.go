@@ -0,0 +1,234 @@
+package filesync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// pathInfo describes path as it will look after already planned actions
+type pathInfo struct {
+	exists bool
+	isLink bool
+	isDir  bool
+	// target is absolute path symlink points to
+	target string
+}
+
+// fsView answers questions about filesystem taking planned directory
+// creation and unfolding into account, so later entries see their effects
+type fsView struct {
+	repoDir string
+	// unfolded maps directories to the link target they replaced
+	unfolded map[string]string
+	created  map[string]bool
+}
+
+func newFsView(repoDir string) *fsView {
+	return &fsView{
+		repoDir:  repoDir,
+		unfolded: map[string]string{},
+		created:  map[string]bool{},
+	}
+}
+
+func (v *fsView) inspect(p string) (pathInfo, error) {
+	parent := filepath.Dir(p)
+	if old, ok := v.unfolded[parent]; ok {
+		oldPath := filepath.Join(old, filepath.Base(p))
+		_, err := os.Lstat(oldPath)
+		if err != nil && os.IsNotExist(err) {
+			return pathInfo{}, nil
+		}
+		if err != nil {
+			return pathInfo{}, err
+		}
+		return pathInfo{exists: true, isLink: true, target: oldPath}, nil
+	}
+	if v.created[parent] {
+		return pathInfo{}, nil
+	}
+
+	fi, err := os.Lstat(p)
+	if err != nil && os.IsNotExist(err) {
+		return pathInfo{}, nil
+	}
+	if err != nil {
+		return pathInfo{}, err
+	}
+
+	info := pathInfo{exists: true, isDir: fi.IsDir()}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		info.isLink = true
+		info.target, err = os.Readlink(p)
+		if err != nil {
+			return pathInfo{}, err
+		}
+		if !filepath.IsAbs(info.target) {
+			info.target = filepath.Join(parent, info.target)
+		}
+	}
+	return info, nil
+}
+
+// planMirror recreates source directory tree in destination with real
+// directories and links only leaf files
+func (pl *planner) planMirror(re ResolvedEntry, ignore []string) ([]Action, error) {
+	actions := []Action{}
+	err := pl.mirrorDir(re.Source, re.Destination, ignore, &actions)
+	if err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func (pl *planner) mirrorDir(src string, dest string, ignore []string, actions *[]Action) error {
+	info, err := pl.view.inspect(dest)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !info.exists:
+		*actions = append(*actions, Action{Kind: ActionCreateDir, Source: src, Destination: dest})
+		pl.view.created[dest] = true
+	case info.isDir:
+	case info.isLink && isWithin(pl.view.repoDir, info.target):
+		if !pl.unfold {
+			*actions = append(*actions, Action{
+				Kind:        ActionConflict,
+				Source:      src,
+				Destination: dest,
+				Reason:      fmt.Sprintf("directory is a link to %s, unfold it first", info.target),
+			})
+			return nil
+		}
+		*actions = append(*actions, Action{
+			Kind:        ActionUnfold,
+			Source:      info.target,
+			Destination: dest,
+			Reason:      fmt.Sprintf("replace link to %s with directory", info.target),
+		})
+		pl.view.unfolded[dest] = info.target
+	default:
+		*actions = append(*actions, Action{
+			Kind:        ActionConflict,
+			Source:      src,
+			Destination: dest,
+			Reason:      "destination exists and is not a directory",
+		})
+		return nil
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, de := range entries {
+		if isIgnored(ignore, de.Name()) {
+			continue
+		}
+		s := filepath.Join(src, de.Name())
+		d := filepath.Join(dest, de.Name())
+		if de.IsDir() {
+			err := pl.mirrorDir(s, d, ignore, actions)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		a, err := pl.mirrorFile(s, d)
+		if err != nil {
+			return err
+		}
+		*actions = append(*actions, a)
+	}
+	return nil
+}
+
+func (pl *planner) mirrorFile(src string, dest string) (Action, error) {
+	info, err := pl.view.inspect(dest)
+	if err != nil {
+		return Action{}, err
+	}
+
+	a := Action{Source: src, Destination: dest}
+	switch {
+	case !info.exists:
+		a.Kind = ActionCreateLink
+	case info.isLink && info.target == src:
+		a.Kind = ActionSkip
+		a.Reason = "already linked"
+	case info.isLink:
+		a.Kind = ActionReplaceLink
+		a.Reason = fmt.Sprintf("links to %s", info.target)
+	case info.isDir:
+		a.Kind = ActionConflict
+		a.Reason = "destination is a directory"
+	default:
+		a.Kind = ActionConflict
+		a.Reason = "destination exists and is not a symlink"
+		a = pl.maybeBackup(a)
+	}
+	return a, nil
+}
+
+// mirrorStatus summarizes state of all files of mirrored entry
+func mirrorStatus(repoDir string, es EntryStatus, ignore []string) (EntryStatus, error) {
+	pl := &planner{view: newFsView(repoDir), unfold: true}
+	actions, err := pl.planMirror(ResolvedEntry{Source: es.Source, Destination: es.Destination}, ignore)
+	if err != nil {
+		return es, err
+	}
+
+	es.State = StateLinked
+	for _, a := range actions {
+		switch a.Kind {
+		case ActionConflict:
+			es.State = StateBlocked
+			es.Reason = fmt.Sprintf("%s: %s", a.Destination, a.Reason)
+			return es, nil
+		case ActionReplaceLink, ActionUnfold:
+			es.State = StateElsewhere
+			es.LinkTarget = a.Source
+		case ActionCreateLink, ActionCreateDir:
+			if es.State == StateLinked {
+				es.State = StateMissing
+			}
+		}
+	}
+	return es, nil
+}
+
+// unfoldLink replaces symlink to directory with real directory
+// containing links to every entry of the original one. Created links are recorded in state.
+func unfoldLink(j *Journal, st *State, dir string, target string) error {
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return err
+	}
+	err = j.remove(dir)
+	if err != nil {
+		return err
+	}
+	err = j.mkdir(dir, 0755)
+	if err != nil {
+		return err
+	}
+	if st != nil {
+		st.DeleteLink(dir)
+	}
+	for _, de := range entries {
+		link, linkTarget := filepath.Join(dir, de.Name()), filepath.Join(target, de.Name())
+		err := j.create(link, func() error {
+			return os.Symlink(linkTarget, link)
+		})
+		if err != nil {
+			return err
+		}
+		if st != nil {
+			st.SetLink(link, linkTarget)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSchema_PlanMirror(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(path.Join(repo, "nvim", "lua"), 0755)
+	_ = os.MkdirAll(path.Join(repo, "shared"), 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "nvim", "init.lua"), []byte("init"), 0644)
+	_ = os.WriteFile(path.Join(repo, "nvim", "lua", "opts.lua"), []byte("opts"), 0644)
+	_ = os.WriteFile(path.Join(repo, "shared", "other.lua"), []byte("other"), 0644)
+	t.Setenv("HOME", home)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+
+	// directory folded by other package
+	dest := path.Join(home, ".config", "nvim")
+	_ = os.MkdirAll(path.Dir(dest), 0755)
+	_ = os.Symlink(path.Join(repo, "shared"), dest)
+
+	s := &Schema{Entries: []SyncDefinition{
+		{Source: "nvim", Destination: "~/.config/nvim", Mirror: true},
+	}}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if len(p.Actions) != 1 || p.Actions[0].Kind != ActionConflict {
+		t.Fatalf("Plan() without unfold = %+v, want single conflict", p)
+	}
+
+	p, err = s.Plan(conf, PlanOptions{Unfold: true})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want := []ActionKind{ActionUnfold, ActionCreateLink, ActionCreateDir, ActionCreateLink}
+	if len(p.Actions) != len(want) {
+		t.Fatalf("Plan() = %+v, want %v", p, want)
+	}
+	for i, a := range p.Actions {
+		if a.Kind != want[i] {
+			t.Errorf("action %d (target: %s) = %s, want %s", i, a.Destination, a.Kind, want[i])
+		}
+	}
+
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	fi, err := os.Lstat(dest)
+	if err != nil || !fi.IsDir() {
+		t.Fatalf("destination is not a real directory (err: %v)", err)
+	}
+	links := map[string]string{
+		"init.lua":     path.Join(repo, "nvim", "init.lua"),
+		"lua/opts.lua": path.Join(repo, "nvim", "lua", "opts.lua"),
+		"other.lua":    path.Join(repo, "shared", "other.lua"),
+	}
+	for name, want := range links {
+		got, err := os.Readlink(path.Join(dest, name))
+		if err != nil || got != want {
+			t.Errorf("link %s = %s (err: %v), want %s", name, got, err, want)
+		}
+	}
+	st, _ := LoadState(conf.stateDir)
+	if st.Links[path.Join(dest, "other.lua")] != links["other.lua"] {
+		t.Errorf("link created by unfold was not recorded in state: %v", st.Links)
+	}
+
+	statuses, err := s.Status(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].State != StateLinked {
+		t.Errorf("Status() after sync = %s, want %s", statuses[0].State, StateLinked)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package filesync
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns uid and gid of file, false when system does not tell them
+func fileOwner(fi os.FileInfo) (int, int, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,97 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+)
+
+var ErrUnknownPackage = errors.New("package is not declared in sync file")
+
+// DEFAULT_IGNORE is used when sync file settings do not define ignore patterns
+var DEFAULT_IGNORE []string = []string{".git", "README*"}
+
+// Package is a repo subdirectory mirrored as a whole into target directory (like stow package)
+type Package struct {
+	// Name of the directory inside repo
+	Name string `yaml:"name"`
+	// Target directory, home dir by default
+	Target string `yaml:"target,omitempty"`
+	// Ignore patterns added to the ones from settings
+	Ignore   []string   `yaml:"ignore,omitempty"`
+	When     *Condition `yaml:"when,omitempty"`
+	Profiles []string   `yaml:"profiles,omitempty"`
+}
+
+// Definition returns mirrored sync definition equivalent to the package
+func (p Package) Definition() SyncDefinition {
+	target := p.Target
+	if target == "" {
+		target = "~"
+	}
+	return SyncDefinition{
+		Source:      p.Name,
+		Destination: target,
+		When:        p.When,
+		Profiles:    p.Profiles,
+		Mirror:      true,
+		Ignore:      p.Ignore,
+	}
+}
+
+// FindPackage returns index of package with given name or -1
+func (s *Schema) FindPackage(name string) int {
+	return slices.IndexFunc(s.Packages, func(p Package) bool {
+		return p.Name == name
+	})
+}
+
+// AddPackage declares package or changes target of declared package with the same name
+// when p sets one. Returns false when sync file stays the same.
+func (s *Schema) AddPackage(p Package) bool {
+	i := s.FindPackage(p.Name)
+	if i < 0 {
+		s.Packages = append(s.Packages, p)
+		return true
+	}
+	if p.Target == "" || s.Packages[i].Target == p.Target {
+		return false
+	}
+	slog.Info("changing package target, sync with prune to remove links in old one", "package", p.Name, "old", s.Packages[i].Target, "new", p.Target)
+	s.Packages[i].Target = p.Target
+	return true
+}
+
+// SelectPackages returns copy of s with only packages with given names and without entries.
+// Vars, hooks and selected profiles of s apply to the copy.
+func (s *Schema) SelectPackages(names []string) (*Schema, error) {
+	res := *s
+	res.Entries = nil
+	res.Packages = nil
+	for _, name := range names {
+		i := s.FindPackage(name)
+		if i < 0 {
+			return nil, fmt.Errorf("(package = %s) %w", name, ErrUnknownPackage)
+		}
+		res.Packages = append(res.Packages, s.Packages[i])
+	}
+	return &res, nil
+}
+
+// ignorePatterns returns patterns of file names left out when mirroring entry
+func (s *Schema) ignorePatterns(sd SyncDefinition) []string {
+	patterns := s.Settings.Ignore
+	if patterns == nil {
+		patterns = DEFAULT_IGNORE
+	}
+	return append(slices.Clone(patterns), sd.Ignore...)
+}
+
+func isIgnored(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		ok, _ := path.Match(p, name)
+		return ok
+	})
+}
@@ -0,0 +1,123 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSchema_PlanPackages(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(path.Join(repo, "zsh", ".git"), 0755)
+	_ = os.MkdirAll(path.Join(repo, "nvim", ".config", "nvim"), 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "zsh", ".zshrc"), []byte("zsh"), 0644)
+	_ = os.WriteFile(path.Join(repo, "zsh", "README.md"), []byte("readme"), 0644)
+	_ = os.WriteFile(path.Join(repo, "zsh", "notes.txt"), []byte("notes"), 0644)
+	_ = os.WriteFile(path.Join(repo, "nvim", ".config", "nvim", "init.lua"), []byte("init"), 0644)
+	t.Setenv("HOME", home)
+	conf := &confMock{path.Join(repo, SYNC_FILE_NAME)}
+
+	s, err := ReadSchema([]byte(`
+version: 2
+packages:
+  - name: zsh
+    ignore: ["*.txt"]
+  - name: nvim
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	links := map[string]bool{}
+	for _, a := range p.Actions {
+		if a.Kind == ActionCreateLink {
+			links[a.Destination] = true
+		}
+	}
+	want := []string{
+		path.Join(home, ".zshrc"),
+		path.Join(home, ".config", "nvim", "init.lua"),
+	}
+	if len(links) != len(want) {
+		t.Fatalf("planned links = %v, want %v", links, want)
+	}
+	for _, w := range want {
+		if !links[w] {
+			t.Errorf("link %s was not planned", w)
+		}
+	}
+
+	sub, err := s.SelectPackages([]string{"nvim"})
+	if err != nil {
+		t.Fatalf("SelectPackages() failed: %v", err)
+	}
+	if len(sub.Packages) != 1 || len(sub.Entries) != 0 {
+		t.Errorf("SelectPackages() = %+v, want only nvim package", sub)
+	}
+	if _, err := s.SelectPackages([]string{"nope"}); err == nil {
+		t.Error("SelectPackages() accepted unknown package")
+	}
+	if s.AddPackage(Package{Name: "zsh"}) {
+		t.Error("AddPackage() added package twice")
+	}
+	if !s.AddPackage(Package{Name: "zsh", Target: "~/.config/zsh"}) || s.Packages[s.FindPackage("zsh")].Target != "~/.config/zsh" {
+		t.Errorf("AddPackage() did not change target of declared package: %+v", s.Packages)
+	}
+}
+
+func TestSchema_SelectPackagesProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(path.Join(repo, "zsh"), 0755)
+	_ = os.MkdirAll(path.Join(repo, "work"), 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "zsh", ".zshrc"), []byte("zsh"), 0644)
+	_ = os.WriteFile(path.Join(repo, "work", ".workrc"), []byte("work"), 0644)
+	t.Setenv("HOME", home)
+
+	s, err := ReadSchema([]byte(`
+version: 2
+vars:
+  zsh_dir: zsh
+profiles:
+  home: {}
+packages:
+  - name: zsh
+    target: ~/${zsh_dir}
+  - name: work
+    profiles: [work]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SelectProfiles([]string{"home"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := s.SelectPackages([]string{"zsh", "work"})
+	if err != nil {
+		t.Fatalf("SelectPackages() failed: %v", err)
+	}
+	p, err := sub.Plan(&confMock{path.Join(repo, SYNC_FILE_NAME)}, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	links := []string{}
+	for _, a := range p.Actions {
+		if a.Kind == ActionCreateLink {
+			links = append(links, a.Destination)
+		}
+	}
+	want := path.Join(home, "zsh", ".zshrc")
+	if len(links) != 1 || links[0] != want {
+		t.Errorf("planned links = %v, want only %s", links, want)
+	}
+}
@@ -0,0 +1,148 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// DEFAULT_DIR_PERM is used for directories created by sync when sync file does not set other
+const DEFAULT_DIR_PERM os.FileMode = 0755
+
+var (
+	ErrInvalidPerm  = errors.New("invalid permissions, use octal notation like 0600")
+	ErrUnknownOwner = errors.New("unknown user or group")
+)
+
+// ParsePerm parses octal permission bits like 0600 or 755
+func ParsePerm(s string) (os.FileMode, error) {
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || v > 0777 {
+		return 0, fmt.Errorf("(perm = %s) %w", s, ErrInvalidPerm)
+	}
+	return os.FileMode(v), nil
+}
+
+// FilePerm returns permissions entry wants enforced, false when it does not set valid ones
+func (sd SyncDefinition) FilePerm() (os.FileMode, bool) {
+	if sd.Perm == "" {
+		return 0, false
+	}
+	perm, err := ParsePerm(sd.Perm)
+	return perm, err == nil
+}
+
+// FileOwner returns uid and gid entry wants enforced, -1 for those it does not set
+func (sd SyncDefinition) FileOwner() (int, int, error) {
+	uid, err := lookupID(sd.Owner, func(name string) (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	})
+	if err != nil {
+		return -1, -1, err
+	}
+	gid, err := lookupID(sd.Group, func(name string) (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	})
+	if err != nil {
+		return -1, -1, err
+	}
+	return uid, gid, nil
+}
+
+// lookupID returns numeric id of user or group name, -1 for empty name
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if name == "" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	id, err := lookup(name)
+	if err != nil {
+		return -1, fmt.Errorf("(name = %s) %w", name, ErrUnknownOwner)
+	}
+	return strconv.Atoi(id)
+}
+
+// dirPerm returns permissions of parent directories created for entry.
+// Invalid values are reported by validate and fall back to DEFAULT_DIR_PERM.
+func (s *Schema) dirPerm(sd SyncDefinition) os.FileMode {
+	for _, p := range []string{sd.DirPerm, s.Settings.DirPerm} {
+		if p == "" {
+			continue
+		}
+		if perm, err := ParsePerm(p); err == nil {
+			return perm
+		}
+		break
+	}
+	return DEFAULT_DIR_PERM
+}
+
+// permPath returns path whose permissions are enforced for entry.
+// Links share permissions with source, copies have their own.
+func permPath(es EntryStatus) string {
+	if es.Definition.LinkMode() == MODE_COPY {
+		return es.Destination
+	}
+	return es.Source
+}
+
+// permStatus reports entry which is otherwise in sync but has wrong permissions, owner or group.
+// Owner of entry is checked to be valid before.
+func permStatus(es EntryStatus) (EntryStatus, error) {
+	want, ok := es.Definition.FilePerm()
+	uid, gid, _ := es.Definition.FileOwner()
+	if (!ok && uid < 0 && gid < 0) || (es.State != StateLinked && es.State != StateCopied) {
+		return es, nil
+	}
+	fi, err := os.Stat(permPath(es))
+	if err != nil {
+		return es, err
+	}
+	curUID, curGID, _ := fileOwner(fi)
+	switch {
+	case ok && fi.Mode().Perm() != want:
+		es.State = StatePermDrift
+		es.Reason = fmt.Sprintf("permissions %04o, want %04o", fi.Mode().Perm(), want)
+	case uid >= 0 && curUID != uid:
+		es.State = StatePermDrift
+		es.Reason = fmt.Sprintf("owner %d, want %d", curUID, uid)
+	case gid >= 0 && curGID != gid:
+		es.State = StatePermDrift
+		es.Reason = fmt.Sprintf("group %d, want %d", curGID, gid)
+	}
+	return es, nil
+}
+
+// missingDirs returns dir and those of its parents which do not exist, outermost first
+func missingDirs(dir string) ([]string, error) {
+	missing := []string{}
+	for ; ; dir = filepath.Dir(dir) {
+		_, err := os.Lstat(dir)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		missing = append(missing, dir)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	slices.Reverse(missing)
+	return missing, nil
+}
@@ -0,0 +1,164 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestParsePerm(t *testing.T) {
+	tests := []struct {
+		perm    string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0600", 0600, false},
+		{"755", 0755, false},
+		{"0", 0, false},
+		{"0800", 0, true},
+		{"01777", 0, true},
+		{"rw-r--r--", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.perm, func(t *testing.T) {
+			got, err := ParsePerm(tt.perm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePerm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePerm() = %04o, want %04o", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_PlanPermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "ssh_config"), []byte("Host *"), 0644)
+	_ = os.WriteFile(path.Join(repo, "token"), []byte("secret"), 0644)
+	linkDest := path.Join(home, ".ssh", "config")
+	copyDest := path.Join(home, ".config", "app", "token")
+	conf := &confMock{path.Join(repo, SYNC_FILE_NAME)}
+	s := &Schema{
+		Settings: Settings{DirPerm: "0750"},
+		Entries: []SyncDefinition{
+			{Source: "ssh_config", Destination: linkDest, Perm: "0600", DirPerm: "0700"},
+			{Source: "token", Destination: copyDest, Mode: MODE_COPY, Perm: "0400"},
+		},
+	}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	perms := []struct {
+		path string
+		want os.FileMode
+	}{
+		{path.Join(repo, "ssh_config"), 0600},
+		{path.Join(home, ".ssh"), 0700},
+		{copyDest, 0400},
+		{path.Join(repo, "token"), 0644},
+		{path.Join(home, ".config"), 0750},
+		{path.Join(home, ".config", "app"), 0750},
+	}
+	for _, tt := range perms {
+		fi, err := os.Stat(tt.path)
+		if err != nil || fi.Mode().Perm() != tt.want {
+			t.Errorf("permissions of %s = %v (err: %v), want %04o", tt.path, fi.Mode().Perm(), err, tt.want)
+		}
+	}
+
+	statuses, _ := s.Status(conf)
+	for _, es := range statuses {
+		if !es.InSync() {
+			t.Fatalf("status of %s = %s (%s)", es.Destination, es.State, es.Reason)
+		}
+	}
+
+	_ = os.Chmod(path.Join(repo, "ssh_config"), 0644)
+	statuses, _ = s.Status(conf)
+	if statuses[0].State != StatePermDrift || statuses[0].Reason != "permissions 0644, want 0600" {
+		t.Fatalf("status = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StatePermDrift)
+	}
+	p, _ = s.Plan(conf, PlanOptions{})
+	if p.Actions[0].Kind != ActionChmod {
+		t.Fatalf("action = %s, want %s", p.Actions[0].Kind, ActionChmod)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if fi, _ := os.Stat(path.Join(repo, "ssh_config")); fi.Mode().Perm() != 0600 {
+		t.Errorf("permissions after sync = %v, want 0600", fi.Mode().Perm())
+	}
+}
+
+func TestSchema_PlanOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owner needs root")
+	}
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.WriteFile(path.Join(repo, "token"), []byte("secret"), 0644)
+	dest := path.Join(home, "token")
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+	s := &Schema{Entries: []SyncDefinition{{Source: "token", Destination: dest, Mode: MODE_COPY, Owner: "65534", Group: "root"}}}
+
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	owner := func() (int, int) {
+		fi, _ := os.Stat(dest)
+		uid, gid, _ := fileOwner(fi)
+		return uid, gid
+	}
+	if uid, gid := owner(); uid != 65534 || gid != 0 {
+		t.Fatalf("owner of destination = %d:%d, want 65534:0", uid, gid)
+	}
+
+	_ = os.Chown(dest, 0, 0)
+	statuses, _ := s.Status(conf)
+	if statuses[0].State != StatePermDrift || statuses[0].Reason != "owner 0, want 65534" {
+		t.Fatalf("status = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StatePermDrift)
+	}
+	p, _ = s.Plan(conf, PlanOptions{})
+	if p.Actions[0].Kind != ActionChmod {
+		t.Fatalf("action = %s, want %s", p.Actions[0].Kind, ActionChmod)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if uid, _ := owner(); uid != 65534 {
+		t.Errorf("owner after sync = %d, want 65534", uid)
+	}
+
+	runs, _ := ListRuns(conf)
+	if _, err := UndoRun(conf, runs[0].ID, false); err != nil {
+		t.Fatalf("UndoRun() failed: %v", err)
+	}
+	if uid, _ := owner(); uid != 0 {
+		t.Errorf("owner after undo = %d, want 0", uid)
+	}
+
+	s.Entries[0].Owner = "no-such-user"
+	statuses, _ = s.Status(conf)
+	if statuses[0].State != StateBlocked || !strings.Contains(statuses[0].Reason, "unknown user") {
+		t.Errorf("status with unknown owner = %s (%s), want %s", statuses[0].State, statuses[0].Reason, StateBlocked)
+	}
+}
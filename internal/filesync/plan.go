@@ -0,0 +1,642 @@
+package filesync
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type ActionKind string
+
+const (
+	ActionCreateLink  ActionKind = "create-link"
+	ActionReplaceLink ActionKind = "replace-link"
+	ActionSkip        ActionKind = "skip"
+	ActionConflict    ActionKind = "conflict"
+	ActionBackupLink  ActionKind = "backup-link"
+	ActionCreateDir   ActionKind = "create-dir"
+	ActionUnfold      ActionKind = "unfold"
+	ActionCopy        ActionKind = "copy"
+	ActionHardlink    ActionKind = "hardlink"
+	ActionChmod       ActionKind = "chmod"
+	ActionHook        ActionKind = "hook"
+	// ActionFailed is planned for entry which could not be inspected when sync keeps going
+	ActionFailed ActionKind = "failed"
+	// ActionPrune removes link created by ftuck for entry which is no longer in sync file
+	ActionPrune ActionKind = "prune"
+	// ActionForget drops record of created link which was since removed or replaced
+	ActionForget ActionKind = "forget"
+)
+
+// Action is a single planned change of the filesystem
+type Action struct {
+	Kind        ActionKind
+	Source      string
+	Destination string
+	Reason      string
+	// Backup is a backup set directory where existing destination is moved
+	Backup string
+	// Mode of entry the action was planned for
+	Mode string
+
+	// state where checksums of copies are recorded
+	state *State
+	// content written instead of copying source and its checksum, see EntryStatus
+	content  []byte
+	checksum string
+	perm     os.FileMode
+	// chmod is enforced on chmodPath after action when chmodPath is set
+	chmod     os.FileMode
+	chmodPath string
+	// uid and gid are enforced on chownPath after action when chownPath is set, -1 keeps them
+	uid, gid  int
+	chownPath string
+	// dirPerm of missing parent directories created for destination
+	dirPerm os.FileMode
+	// group is index of entry action was planned for, -1 for sync file hooks
+	group int
+	hook  *hook
+	// err why entry could not be planned, set for ActionFailed
+	err error
+	// repoDir pruned link must point into, set for ActionPrune
+	repoDir string
+}
+
+// Plan is list of changes planned for configuration with repo and state directories
+type Plan struct {
+	Actions []Action
+
+	repoDir string
+	// stateDir where journal of the run is kept, empty when state is not persisted
+	stateDir string
+	syncFile string
+	// command the run executing the plan is recorded as in history
+	command string
+}
+
+// newPlan returns plan of actions for configuration resolved by r
+func newPlan(r *Resolver, command string, actions []Action) Plan {
+	return Plan{
+		Actions:  actions,
+		repoDir:  r.RepoDir,
+		stateDir: r.StateDir,
+		syncFile: r.SyncFile,
+		command:  command,
+	}
+}
+
+// PlanOptions changes how conflicting entries are handled
+type PlanOptions struct {
+	// BackupDir enables moving existing files out of the way when not empty
+	BackupDir string
+	// Unfold allows replacing directory symlinks pointing into repo
+	// with real directories when mirrored entry needs to put files inside
+	Unfold bool
+	// KeepGoing plans entries which could not be inspected as failed instead of stopping
+	KeepGoing bool
+	// Prune plans removal of links created by ftuck for entries no longer in sync file
+	Prune bool
+	// Issues found by ValidateSchema, entries with issues are not planned
+	// and with KeepGoing they are planned as failed
+	Issues []Issue
+}
+
+// planner keeps state shared between planned entries
+type planner struct {
+	r      *Resolver
+	view   *fsView
+	state  *State
+	setDir string
+	unfold bool
+}
+
+func newPlanner(r *Resolver, opts PlanOptions) (*planner, error) {
+	st, err := LoadState(r.StateDir)
+	if err != nil {
+		return nil, err
+	}
+	pl := &planner{
+		r:      r,
+		view:   newFsView(r.RepoDir),
+		state:  st,
+		unfold: opts.Unfold,
+	}
+	if opts.BackupDir != "" {
+		pl.setDir = NewBackupSetPath(opts.BackupDir, time.Now())
+	}
+	return pl, nil
+}
+
+// Plan inspects every entry and decides what has to be done to sync it.
+// Nothing on disk is changed.
+func (s *Schema) Plan(conf syncFileGetter, opts PlanOptions) (Plan, error) {
+	r, err := s.newResolver(conf)
+	if err != nil {
+		return Plan{}, err
+	}
+	pl, err := newPlanner(r, opts)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	actions := s.hookActions(r, &s.Hooks, HOOK_BEFORE, -1, ResolvedEntry{})
+	group := 0
+	err = s.ForEach(func(sd SyncDefinition) error {
+		re := s.resolve(r, sd)
+		var entryActions []Action
+		invalid := entryIssues(opts.Issues, sd)
+		err := invalid
+		if err == nil {
+			entryActions, err = pl.planDefinition(sd, re, s.ignorePatterns(sd))
+		}
+		if err != nil && !opts.KeepGoing {
+			return err
+		}
+		if err != nil {
+			entryActions = []Action{{Kind: ActionFailed, Source: re.Source, Destination: re.Destination, Reason: err.Error(), err: err}}
+		}
+		for i := range entryActions {
+			entryActions[i].dirPerm = s.dirPerm(sd)
+			entryActions[i].group = group
+			entryActions[i].state = pl.state
+		}
+		if re.SkipReason == "" && invalid == nil {
+			entryActions = append(s.hookActions(r, sd.Hooks, HOOK_BEFORE, group, re), entryActions...)
+			entryActions = append(entryActions, s.hookActions(r, sd.Hooks, HOOK_AFTER, group, re)...)
+			entryActions = append(entryActions, s.hookActions(r, sd.Hooks, HOOK_ON_CHANGE, group, re)...)
+		}
+		actions = append(actions, entryActions...)
+		group++
+		return nil
+	})
+	if err != nil {
+		return Plan{}, err
+	}
+	if opts.Prune {
+		actions = append(actions, s.planPrune(pl, group)...)
+	}
+	actions = append(actions, s.hookActions(r, &s.Hooks, HOOK_ON_CHANGE, -1, ResolvedEntry{})...)
+	actions = append(actions, s.hookActions(r, &s.Hooks, HOOK_AFTER, -1, ResolvedEntry{})...)
+	return newPlan(r, RUN_SYNC, actions), nil
+}
+
+func (pl *planner) planDefinition(sd SyncDefinition, re ResolvedEntry, ignore []string) ([]Action, error) {
+	if sd.Mirror && re.SkipReason == "" {
+		fi, err := os.Stat(re.Source)
+		if err == nil && fi.IsDir() {
+			return pl.planMirror(re, ignore)
+		}
+	}
+
+	es, err := entryStatus(pl.r, sd, re, pl.state)
+	if err != nil {
+		return nil, err
+	}
+	a := planEntry(es)
+	if es.State == StateBlocked && !es.unsupported {
+		a = pl.maybeBackup(a)
+	}
+	return []Action{a}, nil
+}
+
+// maybeBackup turns conflict into backup and link when backups are enabled
+func (pl *planner) maybeBackup(a Action) Action {
+	if pl.setDir == "" {
+		return a
+	}
+	a.Kind = ActionBackupLink
+	a.Backup = pl.setDir
+	a.Reason = fmt.Sprintf("existing file moved to %s", backupPath(pl.setDir, a.Destination))
+	return a
+}
+
+func planEntry(es EntryStatus) Action {
+	a := Action{
+		Source:      es.Source,
+		Destination: es.Destination,
+		Mode:        es.Definition.LinkMode(),
+		content:     es.content,
+		checksum:    es.Checksum,
+		perm:        es.perm,
+	}
+	if perm, ok := es.Definition.FilePerm(); ok {
+		a.chmod = perm
+		a.chmodPath = permPath(es)
+	}
+	if uid, gid, _ := es.Definition.FileOwner(); uid >= 0 || gid >= 0 {
+		a.uid, a.gid = uid, gid
+		a.chownPath = permPath(es)
+	}
+	switch es.State {
+	case StateMissing:
+		a.Kind = createKind(a.Mode)
+	case StateElsewhere:
+		a.Kind = ActionReplaceLink
+		if a.Mode != MODE_SYMLINK {
+			a.Kind = createKind(a.Mode)
+		}
+		a.Reason = fmt.Sprintf("links to %s", es.LinkTarget)
+	case StateBlocked:
+		a.Kind = ActionConflict
+		a.Reason = es.Reason
+		if a.Reason == "" {
+			a.Reason = "destination exists and is not a link to source"
+		}
+	case StateSourceMissing:
+		a.Kind = ActionConflict
+		a.Reason = "source does not exist"
+	case StateDrifted:
+		a.Kind = ActionConflict
+		a.Reason = fmt.Sprintf("%s since last sync, check diff and pull", es.Drift)
+		if es.Drift == DriftSource {
+			a.Kind = ActionCopy
+		}
+	case StatePermDrift:
+		a.Kind = ActionChmod
+		a.Reason = es.Reason
+	case StateSkipped:
+		a.Kind = ActionSkip
+		a.Reason = es.Reason
+	default:
+		a.Kind = ActionSkip
+		a.Reason = fmt.Sprintf("already %s", es.State)
+	}
+	return a
+}
+
+// createKind returns action creating destination in given mode
+func createKind(mode string) ActionKind {
+	switch mode {
+	case MODE_COPY:
+		return ActionCopy
+	case MODE_HARDLINK:
+		return ActionHardlink
+	default:
+		return ActionCreateLink
+	}
+}
+
+// Execute applies planned actions in order. On first error every change
+// made so far is rolled back.
+func (p Plan) Execute() error {
+	return p.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is Execute which also rolls back when ctx is cancelled.
+// Changes are journaled in state dir so sync killed in the middle can be
+// rolled back with RecoverSync.
+func (p Plan) ExecuteContext(ctx context.Context) error {
+	_, err := p.run(ctx, false)
+	return err
+}
+
+// ExecuteKeepGoing applies every entry even when some of them fail. Changes of failed
+// entry are rolled back and the rest is kept. Returned error joins every failure,
+// conflicts included. Cancelled ctx still rolls back the whole sync.
+func (p Plan) ExecuteKeepGoing(ctx context.Context) ([]Failure, error) {
+	return p.run(ctx, true)
+}
+
+func (p Plan) run(ctx context.Context, keepGoing bool) ([]Failure, error) {
+	j, err := beginJournal(p.stateDir)
+	if err != nil {
+		return nil, err
+	}
+	before, linksBefore := p.checksums(), p.links()
+	failures, err := p.execute(ctx, j, keepGoing)
+	if err != nil {
+		slog.Error("sync failed, rolling back", "error", err, "changes", len(j.Records))
+		return failures, errors.Join(err, j.Rollback())
+	}
+
+	changed := map[string]string{}
+	for dest, sum := range p.checksums() {
+		if before[dest] != sum {
+			changed[dest] = before[dest]
+		}
+	}
+	run := Run{Command: p.command, Checksums: changed, Links: changedLinks(linksBefore, p.links()), syncFile: p.syncFile}
+	if len(j.Records) > 0 {
+		run.Commit = repoCommit(p.repoDir)
+	}
+	// state is saved only once the journal is closed, sync interrupted before
+	// is rolled back by recover to the state it started with
+	errs := []error{j.finish(run)}
+	if errs[0] == nil {
+		errs = append(errs, p.saveState())
+	}
+	for _, f := range failures {
+		errs = append(errs, f)
+	}
+	return failures, errors.Join(errs...)
+}
+
+func (p Plan) execute(ctx context.Context, j *Journal, keepGoing bool) ([]Failure, error) {
+	// changed entries by group, on_change hooks run only for them
+	changed := map[int]bool{}
+	failed := map[int]bool{}
+	failures := []Failure{}
+	// journal position, checksums and created links at the start of current entry
+	mark, checksums, links := journalMark{}, map[string]string{}, map[string]string{}
+	for i, a := range p.Actions {
+		if err := ctx.Err(); err != nil {
+			return failures, err
+		}
+		if i == 0 || a.group != p.Actions[i-1].group {
+			mark, checksums, links = j.mark(), p.groupChecksums(i), p.links()
+		}
+		if failed[a.group] {
+			continue
+		}
+
+		err := a.run(ctx, j, changed)
+		if err == nil && keepGoing && a.Kind == ActionConflict {
+			err = fmt.Errorf("%w: %s", ErrConflict, a.Reason)
+		}
+		if err == nil {
+			if a.Kind.changes() {
+				changed[a.group] = true
+				j.done(a)
+			}
+			continue
+		}
+		if !keepGoing {
+			slog.Error("syncing", "error", err, "target", a.Destination)
+			return failures, err
+		}
+
+		slog.Error("syncing failed, keeping going", "error", err, "target", a.Destination)
+		failures = append(failures, newFailure(a, err))
+		err = j.rollbackTo(mark)
+		if err != nil {
+			return failures, err
+		}
+		p.restoreChecksums(checksums)
+		p.restoreLinks(links)
+		if a.group >= 0 {
+			failed[a.group] = true
+			delete(changed, a.group)
+		}
+	}
+	return failures, nil
+}
+
+// run executes action or hook, on_change hooks are skipped when their entry did not change
+func (a Action) run(ctx context.Context, j *Journal, changed map[int]bool) error {
+	switch a.Kind {
+	case ActionHook:
+		if a.hook.trigger == HOOK_ON_CHANGE && !changed[a.group] && !(a.group < 0 && len(changed) > 0) {
+			slog.Info("skipping hook, nothing changed", "command", a.hook.command)
+			return nil
+		}
+		return a.hook.run(ctx)
+	case ActionFailed:
+		return a.err
+	default:
+		return a.execute(j)
+	}
+}
+
+// groupChecksums returns recorded checksums of destinations of entry starting at action i
+func (p Plan) groupChecksums(i int) map[string]string {
+	res := map[string]string{}
+	for _, a := range p.Actions[i:] {
+		if a.group != p.Actions[i].group {
+			break
+		}
+		if a.state != nil {
+			res[a.Destination] = a.state.Checksums[a.Destination]
+		}
+	}
+	return res
+}
+
+// checksums returns recorded checksums of planned destinations
+func (p Plan) checksums() map[string]string {
+	res := map[string]string{}
+	for _, a := range p.Actions {
+		if a.state != nil {
+			res[a.Destination] = a.state.Checksums[a.Destination]
+		}
+	}
+	return res
+}
+
+// restoreChecksums puts back checksums of rolled back entry
+func (p Plan) restoreChecksums(checksums map[string]string) {
+	st := p.state()
+	if st == nil {
+		return
+	}
+	for dest, sum := range checksums {
+		if st.Checksums[dest] == sum {
+			continue
+		}
+		if sum == "" {
+			st.DeleteChecksum(dest)
+		} else {
+			st.SetChecksum(dest, sum)
+		}
+	}
+}
+
+// links returns copy of links created by ftuck recorded in state
+func (p Plan) links() map[string]string {
+	st := p.state()
+	if st == nil {
+		return map[string]string{}
+	}
+	return maps.Clone(st.Links)
+}
+
+// restoreLinks puts back records of links changed by rolled back entry
+func (p Plan) restoreLinks(links map[string]string) {
+	st := p.state()
+	if st == nil {
+		return
+	}
+	for dest, target := range changedLinks(links, st.Links) {
+		if target == "" {
+			st.DeleteLink(dest)
+		} else {
+			st.SetLink(dest, target)
+		}
+	}
+}
+
+// changedLinks returns previous targets of link records which differ after,
+// empty target for links which were not recorded before
+func changedLinks(before, after map[string]string) map[string]string {
+	res := map[string]string{}
+	for dest, target := range after {
+		if before[dest] != target {
+			res[dest] = before[dest]
+		}
+	}
+	for dest, target := range before {
+		if _, ok := after[dest]; !ok {
+			res[dest] = target
+		}
+	}
+	return res
+}
+
+// state returns state shared by planned actions
+func (p Plan) state() *State {
+	for _, a := range p.Actions {
+		if a.state != nil {
+			return a.state
+		}
+	}
+	return nil
+}
+
+// saveState persists state shared by planned actions
+func (p Plan) saveState() error {
+	st := p.state()
+	if st == nil {
+		return nil
+	}
+	return st.Save()
+}
+
+// execute applies action, destinations get missing parents created
+// and permissions enforced when entry sets them
+func (a Action) execute(j *Journal) error {
+	switch a.Kind {
+	case ActionCreateLink, ActionReplaceLink, ActionCopy, ActionHardlink, ActionBackupLink:
+		err := j.mkdirAll(filepath.Dir(a.Destination), a.dirPerm)
+		if err != nil {
+			return err
+		}
+		err = a.apply(j)
+		if err != nil {
+			return err
+		}
+		return a.enforcePerm(j)
+	default:
+		return a.apply(j)
+	}
+}
+
+func (a Action) enforcePerm(j *Journal) error {
+	if a.chmodPath != "" {
+		err := j.chmod(a.chmodPath, a.chmod)
+		if err != nil {
+			return err
+		}
+	}
+	if a.chownPath != "" {
+		return j.chown(a.chownPath, a.uid, a.gid)
+	}
+	return nil
+}
+
+func (a Action) apply(j *Journal) error {
+	switch a.Kind {
+	case ActionCreateLink:
+		return a.deploy(j)
+	case ActionReplaceLink:
+		slog.Info("replacing link", "source", a.Source, "target", a.Destination, "reason", a.Reason)
+		err := j.remove(a.Destination)
+		if err != nil {
+			return err
+		}
+		return a.deploy(j)
+	case ActionCopy, ActionHardlink:
+		return a.replace(j)
+	case ActionCreateDir:
+		slog.Info("creating directory", "target", a.Destination)
+		return j.mkdirAll(a.Destination, a.dirPerm)
+	case ActionChmod:
+		slog.Info("changing permissions", "path", cmp.Or(a.chmodPath, a.chownPath), "reason", a.Reason)
+		return a.enforcePerm(j)
+	case ActionUnfold:
+		slog.Info("unfolding directory link", "target", a.Destination, "link", a.Source)
+		return unfoldLink(j, a.state, a.Destination, a.Source)
+	case ActionBackupLink:
+		err := backupFile(j, a.Backup, a.Destination)
+		if err != nil {
+			return err
+		}
+		return a.deploy(j)
+	case ActionPrune:
+		return a.prune(j)
+	case ActionForget:
+		slog.Info("forgetting link", "target", a.Destination, "reason", a.Reason)
+		a.state.DeleteLink(a.Destination)
+		return nil
+	case ActionConflict:
+		slog.Error("conflict", "target", a.Destination, "reason", a.Reason)
+		return nil
+	default:
+		slog.Info("nothing to do", "target", a.Destination)
+		return nil
+	}
+}
+
+// replace removes link or outdated copy at destination and deploys source again
+func (a Action) replace(j *Journal) error {
+	_, err := os.Lstat(a.Destination)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		err = j.remove(a.Destination)
+		if err != nil {
+			return err
+		}
+	}
+	return a.deploy(j)
+}
+
+// deploy puts source in destination according to entry mode
+func (a Action) deploy(j *Journal) error {
+	switch a.Mode {
+	case MODE_COPY:
+		err := j.create(a.Destination, func() error {
+			if a.content != nil {
+				slog.Info("writing", "source", a.Source, "target", a.Destination)
+				return os.WriteFile(a.Destination, a.content, a.perm)
+			}
+			slog.Info("copying", "source", a.Source, "target", a.Destination)
+			return copyPath(a.Source, a.Destination)
+		})
+		if err != nil {
+			return err
+		}
+		if a.state == nil {
+			return nil
+		}
+		sum := a.checksum
+		if a.content == nil {
+			sum, err = Checksum(a.Destination)
+			if err != nil {
+				return err
+			}
+		}
+		a.state.SetChecksum(a.Destination, sum)
+		return nil
+	case MODE_HARDLINK:
+		slog.Info("creating hardlink", "source", a.Source, "target", a.Destination)
+		return j.create(a.Destination, func() error {
+			return os.Link(a.Source, a.Destination)
+		})
+	default:
+		slog.Info("creating link", "source", a.Source, "target", a.Destination)
+		err := j.create(a.Destination, func() error {
+			return os.Symlink(a.Source, a.Destination)
+		})
+		if err != nil || a.state == nil {
+			return err
+		}
+		a.state.SetLink(a.Destination, a.Source)
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSchema_Plan(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcPath := path.Join(tmpDir, "src")
+	destPath := path.Join(tmpDir, "dest")
+	_ = os.MkdirAll(srcPath, 0755)
+	_ = os.MkdirAll(destPath, 0755)
+
+	srcF1Name := path.Join(srcPath, "file1")
+	_ = os.WriteFile(srcF1Name, []byte("file1"), 0644)
+	_ = os.Symlink(srcF1Name, path.Join(destPath, "linked"))
+	_ = os.Symlink(path.Join(tmpDir, "nowhere"), path.Join(destPath, "elsewhere"))
+	_ = os.WriteFile(path.Join(destPath, "blocked"), []byte("blocked"), 0644)
+
+	s := &Schema{Entries: []SyncDefinition{
+		{Source: "file1", Destination: path.Join(destPath, "new")},
+		{Source: "file1", Destination: path.Join(destPath, "elsewhere")},
+		{Source: "file1", Destination: path.Join(destPath, "linked")},
+		{Source: "file1", Destination: path.Join(destPath, "blocked")},
+	}}
+	want := []ActionKind{ActionCreateLink, ActionReplaceLink, ActionSkip, ActionConflict}
+
+	p, err := s.Plan(&confMock{path.Join(srcPath, SYNC_FILE_NAME)}, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if len(p.Actions) != len(want) {
+		t.Fatalf("Plan() returned %d actions, want %d", len(p.Actions), len(want))
+	}
+	for i, a := range p.Actions {
+		if a.Kind != want[i] {
+			t.Errorf("action %d (target: %s) = %s, want %s", i, a.Destination, a.Kind, want[i])
+		}
+	}
+
+	// planning must not touch the filesystem
+	if _, err := os.Lstat(path.Join(destPath, "new")); !os.IsNotExist(err) {
+		t.Errorf("Plan() created destination: %v", err)
+	}
+
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	for _, name := range []string{"new", "elsewhere"} {
+		got, err := os.Readlink(path.Join(destPath, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != srcF1Name {
+			t.Errorf("link %s points to %s, want %s", name, got, srcF1Name)
+		}
+	}
+}
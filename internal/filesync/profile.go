@@ -0,0 +1,91 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnknownProfile = errors.New("unknown profile")
+	ErrProfileCycle   = errors.New("profiles include each other")
+)
+
+// Profile groups entries, it can pull in entries of other profiles
+type Profile struct {
+	Include []string `yaml:"include,omitempty"`
+}
+
+// SelectProfiles activates given profiles with all profiles they include.
+// Only entries without profiles or belonging to active ones are synced afterwards.
+// Empty list selects all entries.
+func (s *Schema) SelectProfiles(names []string) error {
+	if len(names) == 0 {
+		s.active = nil
+		return nil
+	}
+
+	active := map[string]bool{}
+	for _, name := range names {
+		err := s.activateProfile(active, name, []string{})
+		if err != nil {
+			return err
+		}
+	}
+	s.active = active
+	return nil
+}
+
+func (s *Schema) activateProfile(active map[string]bool, name string, path []string) error {
+	if slices.Contains(path, name) {
+		return fmt.Errorf("(%s -> %s) %w", strings.Join(path, " -> "), name, ErrProfileCycle)
+	}
+	p, ok := s.Profiles[name]
+	if !ok && !s.isUsedProfile(name) {
+		return fmt.Errorf("(profile = %s) %w", name, ErrUnknownProfile)
+	}
+	active[name] = true
+	for _, inc := range p.Include {
+		err := s.activateProfile(active, inc, append(path, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isUsedProfile checks if any entry or package refers to profile, so profiles
+// without includes do not have to be declared
+func (s *Schema) isUsedProfile(name string) bool {
+	return slices.ContainsFunc(s.Entries, func(sd SyncDefinition) bool {
+		return slices.Contains(sd.Profiles, name)
+	}) || slices.ContainsFunc(s.Packages, func(p Package) bool {
+		return slices.Contains(p.Profiles, name)
+	})
+}
+
+// profileSkipReason explains why entry is not in any of active profiles
+func (s *Schema) profileSkipReason(sd SyncDefinition) string {
+	if s.active == nil || len(sd.Profiles) == 0 {
+		return ""
+	}
+	for _, p := range sd.Profiles {
+		if s.active[p] {
+			return ""
+		}
+	}
+	return fmt.Sprintf("profiles [%s] not active", strings.Join(sd.Profiles, " "))
+}
+
+// ParseProfileList splits comma separated list of profiles
+func ParseProfileList(list string) []string {
+	res := []string{}
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
@@ -0,0 +1,145 @@
+package filesync
+
+import (
+	"errors"
+	"testing"
+)
+
+const profilesSchema = `
+version: 2
+profiles:
+  work:
+    include: [base]
+  laptop:
+    include: [work, gui]
+  loop-a:
+    include: [loop-b]
+  loop-b:
+    include: [loop-a]
+packages:
+  - name: steam
+    profiles: [games]
+entries:
+  - src: bashrc
+    dest: ~/.bashrc
+  - src: gitconfig
+    dest: ~/.gitconfig
+    profiles: [base]
+  - src: work.env
+    dest: ~/.work.env
+    profiles: [work]
+  - src: sway
+    dest: ~/.config/sway
+    profiles: [gui]
+`
+
+func TestReadSchema(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		data string
+		want int
+	}{
+		{name: "empty file", data: "", want: 0},
+		{name: "bare list", data: "- src: a\n  dest: b\n- src: c\n  dest: d\n", want: 2},
+		{name: "versioned document", data: profilesSchema, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ReadSchema([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ReadSchema() failed: %v", err)
+			}
+			if len(s.Entries) != tt.want {
+				t.Errorf("ReadSchema() read %d entries, want %d", len(s.Entries), tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_SelectProfiles(t *testing.T) {
+	tests := []struct {
+		name     string // description of this test case
+		profiles []string
+		wantErr  error
+		// destinations of entries which should be synced
+		want []string
+	}{
+		{
+			name:     "no profiles selects everything",
+			profiles: nil,
+			want:     []string{"~/.bashrc", "~/.gitconfig", "~/.work.env", "~/.config/sway"},
+		},
+		{
+			name:     "included profiles are active",
+			profiles: []string{"work"},
+			want:     []string{"~/.bashrc", "~/.gitconfig", "~/.work.env"},
+		},
+		{
+			name:     "nested includes and undeclared profile",
+			profiles: []string{"laptop"},
+			want:     []string{"~/.bashrc", "~/.gitconfig", "~/.work.env", "~/.config/sway"},
+		},
+		{
+			name:     "profile used only by entries",
+			profiles: []string{"gui"},
+			want:     []string{"~/.bashrc", "~/.config/sway"},
+		},
+		{
+			name:     "profile used only by packages",
+			profiles: []string{"games"},
+			want:     []string{"~/.bashrc"},
+		},
+		{
+			name:     "unknown profile",
+			profiles: []string{"nope"},
+			wantErr:  ErrUnknownProfile,
+		},
+		{
+			name:     "include cycle",
+			profiles: []string{"loop-a"},
+			wantErr:  ErrProfileCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ReadSchema([]byte(profilesSchema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SelectProfiles(tt.profiles)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SelectProfiles() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := []string{}
+			for _, sd := range s.Entries {
+				if s.profileSkipReason(sd) == "" {
+					got = append(got, sd.Destination)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("synced entries = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("synced entries = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProfileList(t *testing.T) {
+	got := ParseProfileList(" work, base,,gui ")
+	want := []string{"work", "base", "gui"}
+	if len(got) != len(want) {
+		t.Fatalf("ParseProfileList() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("ParseProfileList() = %v, want %v", got, want)
+		}
+	}
+}
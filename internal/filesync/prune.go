@@ -0,0 +1,117 @@
+package filesync
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// claims tells if entry still owns destination. Mirrored entries own links
+// inside their destination as long as the mirrored source file exists.
+func claims(sd SyncDefinition, re ResolvedEntry, dest string) bool {
+	if re.Destination == dest {
+		return true
+	}
+	if !sd.Mirror || !isWithin(re.Destination, dest) {
+		return false
+	}
+	rel, _ := filepath.Rel(re.Destination, dest)
+	_, err := os.Lstat(filepath.Join(re.Source, rel))
+	return err == nil
+}
+
+// planPrune plans removal of links created by ftuck which no entry owns anymore.
+// Link is removed only when it still points where ftuck pointed it and that is inside repo,
+// records of links which were removed or replaced since are just forgotten.
+// Links pointing outside of repo are skipped and their records kept.
+func (s *Schema) planPrune(pl *planner, group int) []Action {
+	dests := []string{}
+	for dest := range pl.state.Links {
+		claimed := false
+		s.ForEach(func(sd SyncDefinition) error {
+			claimed = claimed || claims(sd, s.resolve(pl.r, sd), dest)
+			return nil
+		})
+		if !claimed {
+			dests = append(dests, dest)
+		}
+	}
+	slices.Sort(dests)
+
+	actions := []Action{}
+	for _, dest := range dests {
+		a := Action{
+			Kind:        ActionPrune,
+			Source:      pl.state.Links[dest],
+			Destination: dest,
+			Reason:      "entry is no longer in sync file",
+			state:       pl.state,
+			group:       group,
+			repoDir:     pl.r.RepoDir,
+		}
+		if reason, forget := orphanReason(pl.r, a); reason != "" {
+			a.Kind = ActionSkip
+			if forget {
+				a.Kind = ActionForget
+			}
+			a.Reason = reason
+		}
+		actions = append(actions, a)
+		group++
+	}
+	return actions
+}
+
+// orphanReason tells why link recorded as created by ftuck must not be removed and if its
+// record should be forgotten. Links pointing outside of repo may belong to other repo
+// sharing the state so their records are kept.
+func orphanReason(r *Resolver, a Action) (string, bool) {
+	fi, err := os.Lstat(a.Destination)
+	if err != nil {
+		return "link no longer exists", true
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		return "destination is no longer a link", true
+	}
+	target, err := os.Readlink(a.Destination)
+	if err != nil || target != a.Source {
+		return fmt.Sprintf("link was changed to %s", target), true
+	}
+	if !isWithin(r.RepoDir, target) {
+		return "link does not point into repo", false
+	}
+	return "", false
+}
+
+// prune removes orphaned link, it is checked again right before removal
+func (a Action) prune(j *Journal) error {
+	if reason, forget := orphanReason(&Resolver{RepoDir: a.repoDir}, a); reason != "" {
+		slog.Warn("not pruning", "target", a.Destination, "reason", reason)
+		if forget {
+			a.state.DeleteLink(a.Destination)
+		}
+		return nil
+	}
+	slog.Info("pruning link", "target", a.Destination, "link", a.Source)
+	err := j.remove(a.Destination)
+	if err != nil {
+		return err
+	}
+	a.state.DeleteLink(a.Destination)
+	return nil
+}
+
+// Prune plans removal of orphaned links only, without syncing entries
+func (s *Schema) Prune(conf syncFileGetter) (Plan, error) {
+	r, err := s.newResolver(conf)
+	if err != nil {
+		return Plan{}, err
+	}
+	pl, err := newPlanner(r, PlanOptions{})
+	if err != nil {
+		return Plan{}, err
+	}
+	return newPlan(r, RUN_PRUNE, s.planPrune(pl, 0)), nil
+}
@@ -0,0 +1,109 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSchema_Prune(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	stateDir := path.Join(tmpDir, "state")
+	_ = os.MkdirAll(path.Join(repo, "nvim"), 0755)
+	_ = os.MkdirAll(home, 0755)
+	for _, f := range []string{"bashrc", "vimrc", "zshrc", "tmux.conf", "nvim/init.lua", "nvim/old.lua"} {
+		_ = os.WriteFile(path.Join(repo, f), []byte(f), 0644)
+	}
+	t.Setenv("HOME", home)
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, stateDir}
+	s := &Schema{
+		Entries: []SyncDefinition{
+			{Source: "bashrc", Destination: ".bashrc"},
+			{Source: "vimrc", Destination: ".vimrc"},
+			{Source: "zshrc", Destination: ".zshrc"},
+			{Source: "tmux.conf", Destination: ".tmux.conf"},
+			{Source: "nvim", Destination: ".config/nvim", Mirror: true},
+		},
+	}
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	// link not created by ftuck
+	_ = os.Symlink(path.Join(repo, "bashrc"), path.Join(home, ".profile"))
+	// links changed after sync
+	_ = os.Remove(path.Join(home, ".zshrc"))
+	_ = os.Remove(path.Join(home, ".tmux.conf"))
+	_ = os.Symlink("/elsewhere", path.Join(home, ".tmux.conf"))
+	_ = os.Remove(path.Join(repo, "nvim/old.lua"))
+	// link recorded for other repo sharing the state
+	other := path.Join(tmpDir, "other", "gitconfig")
+	_ = os.MkdirAll(path.Dir(other), 0755)
+	_ = os.WriteFile(other, []byte("other"), 0644)
+	_ = os.Symlink(other, path.Join(home, ".gitconfig"))
+	st, _ := LoadState(stateDir)
+	st.SetLink(path.Join(home, ".gitconfig"), other)
+	_ = st.Save()
+	s.Entries = []SyncDefinition{
+		{Source: "bashrc", Destination: ".bashrc"},
+		{Source: "nvim", Destination: ".config/nvim", Mirror: true},
+	}
+
+	p, err = s.Prune(conf)
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	want := map[string]ActionKind{
+		path.Join(home, ".config/nvim/old.lua"): ActionPrune,
+		path.Join(home, ".tmux.conf"):           ActionForget,
+		path.Join(home, ".vimrc"):               ActionPrune,
+		path.Join(home, ".zshrc"):               ActionForget,
+		path.Join(home, ".gitconfig"):           ActionSkip,
+	}
+	if len(p.Actions) != len(want) {
+		t.Fatalf("Prune() = %v, want %d actions", p, len(want))
+	}
+	for _, a := range p.Actions {
+		if want[a.Destination] != a.Kind {
+			t.Errorf("Prune() planned %s for %s, want %s", a.Kind, a.Destination, want[a.Destination])
+		}
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	for _, f := range []string{".vimrc", ".config/nvim/old.lua"} {
+		if _, err := os.Lstat(path.Join(home, f)); !os.IsNotExist(err) {
+			t.Errorf("orphaned link %s was not removed", f)
+		}
+	}
+	for _, f := range []string{".bashrc", ".profile", ".tmux.conf", ".config/nvim/init.lua"} {
+		if _, err := os.Lstat(path.Join(home, f)); err != nil {
+			t.Errorf("link %s was removed: %v", f, err)
+		}
+	}
+	st, _ = LoadState(stateDir)
+	if len(st.Links) != 3 || st.Links[path.Join(home, ".gitconfig")] != other {
+		t.Errorf("state links = %v, want .bashrc, init.lua and .gitconfig", st.Links)
+	}
+	if p, _ := s.Prune(conf); len(p.Actions) != 1 || p.Actions[0].Kind != ActionSkip {
+		t.Errorf("second Prune() = %v, want only .gitconfig skipped", p)
+	}
+
+	runs, _ := ListRuns(conf)
+	if len(runs) == 0 || runs[0].Command != RUN_PRUNE {
+		t.Fatalf("ListRuns() = %+v, want prune run first", runs)
+	}
+	if _, err := UndoRun(conf, runs[0].ID, false); err != nil {
+		t.Fatalf("UndoRun(prune) failed: %v", err)
+	}
+	st, _ = LoadState(stateDir)
+	if st.Links[path.Join(home, ".vimrc")] != path.Join(repo, "vimrc") || len(st.Links) != 7 {
+		t.Errorf("state links after undo = %v, want records of pruned links back", st.Links)
+	}
+}
@@ -0,0 +1,129 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrBothChanged  = errors.New("source and destination changed since last sync")
+	ErrPullTemplate = errors.New("templates cannot be pulled, edit the template in repo instead")
+	ErrPullBlocked  = errors.New("destination was not copied by ftuck, pull it with force")
+)
+
+// CopiedEntries returns statuses of copied entries with given destinations,
+// all of them when no targets are given
+func (s *Schema) CopiedEntries(conf syncFileGetter, targets []string) ([]EntryStatus, error) {
+	statuses, err := s.Status(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []EntryStatus{}
+	for _, es := range statuses {
+		if es.Definition.LinkMode() != MODE_COPY || es.State == StateSkipped {
+			continue
+		}
+		if len(targets) > 0 && !matchesAnyTarget(es, targets) {
+			continue
+		}
+		res = append(res, es)
+	}
+	return res, nil
+}
+
+func matchesAnyTarget(es EntryStatus, targets []string) bool {
+	for _, trg := range targets {
+		abs, _ := filepath.Abs(trg)
+		if es.Definition.Destination == trg || es.Destination == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// Pull copies changed destinations of copied entries back into repo.
+// Entries changed on both sides are refused unless force is set.
+func (s *Schema) Pull(conf syncFileGetter, targets []string, force bool) ([]EntryStatus, error) {
+	entries, err := s.CopiedEntries(conf, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	toPull := []EntryStatus{}
+	for _, es := range entries {
+		switch {
+		case es.Definition.Template && len(targets) > 0 && es.State != StateCopied:
+			return nil, fmt.Errorf("(target = %s) %w", es.Destination, ErrPullTemplate)
+		case es.Definition.Template, es.unsupported:
+			continue
+		case es.State == StateDrifted && es.Drift == DriftDestination:
+		case es.State == StateDrifted && es.Drift == DriftBoth:
+			if !force {
+				return nil, fmt.Errorf("(target = %s) %w", es.Destination, ErrBothChanged)
+			}
+		case es.State == StateBlocked:
+			if !force {
+				return nil, fmt.Errorf("(target = %s, reason = %s) %w", es.Destination, es.Reason, ErrPullBlocked)
+			}
+		default:
+			continue
+		}
+		toPull = append(toPull, es)
+	}
+
+	r := NewResolver(conf)
+	st, err := LoadState(r.StateDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, es := range toPull {
+		err := pullEntry(r, es, st)
+		if err != nil {
+			return nil, errors.Join(err, st.Save())
+		}
+	}
+	return toPull, st.Save()
+}
+
+func pullEntry(r *Resolver, es EntryStatus, st *State) error {
+	slog.Info("pulling", "target", es.Destination, "source", es.Source)
+	if es.Definition.Encrypted {
+		return pullEncrypted(r, es, st)
+	}
+	err := replacePath(es.Destination, es.Source)
+	if err != nil {
+		return err
+	}
+	sum, err := Checksum(es.Destination)
+	if err != nil {
+		return err
+	}
+	st.SetChecksum(es.Destination, sum)
+	return nil
+}
+
+// pullEncrypted encrypts destination back into repo
+func pullEncrypted(r *Resolver, es EntryStatus, st *State) error {
+	id, err := LoadIdentity(r.IdentityFile)
+	if err != nil {
+		return err
+	}
+	d, err := os.ReadFile(es.Destination)
+	if err != nil {
+		return err
+	}
+	err = EncryptFile(id, d, es.Source)
+	if err != nil {
+		return err
+	}
+	sum, err := id.checksum(d)
+	if err != nil {
+		return err
+	}
+	st.SetChecksum(es.Destination, sum)
+	return nil
+}
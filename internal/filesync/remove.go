@@ -0,0 +1,247 @@
+package filesync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrNoMatchingEntry = errors.New("no matching entry in sync file")
+	ErrCannotUnlink    = errors.New("destination changed since sync, remove it by hand")
+)
+
+// RemoveOptions decides what happens on disk with removed entries
+type RemoveOptions struct {
+	// Unlink removes what sync put in destination: symlinks pointing into repo,
+	// links to files of mirrored directories, hardlinks and unchanged copies
+	Unlink bool
+	// Restore puts a copy of repo file in place of removed link, copies are kept
+	Restore bool
+}
+
+// RemoveFromSyncFile deletes definitions with given source or target from sync file.
+// Empty src or trg is not matched.
+func RemoveFromSyncFile(conf syncFileGetter, src string, trg string, opts RemoveOptions) ([]SyncDefinition, error) {
+	syncFile := conf.GetSyncFilePath()
+	d, err := ReadOrCreate(syncFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w : run init again", err)
+	}
+
+	s, err := ReadSchema(d)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.newResolver(conf)
+	if err != nil {
+		return nil, err
+	}
+	removed := s.Remove(func(sd SyncDefinition) bool {
+		return matchesEntry(r, sd, src, trg)
+	})
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("(source = %s, target = %s) %w", src, trg, ErrNoMatchingEntry)
+	}
+
+	j, err := beginJournal(stateDir(conf))
+	if err != nil {
+		return nil, err
+	}
+	err = removeEntries(j, r, s, syncFile, removed, opts)
+	if err != nil {
+		return nil, errors.Join(err, j.Rollback())
+	}
+	return removed, j.finish(Run{Command: RUN_REMOVE, Commit: repoCommit(r.RepoDir), syncFile: syncFile})
+}
+
+func removeEntries(j *Journal, r *Resolver, s *Schema, syncFile string, removed []SyncDefinition, opts RemoveOptions) error {
+	if opts.Unlink || opts.Restore {
+		st, err := LoadState(r.StateDir)
+		if err != nil {
+			return err
+		}
+		for _, sd := range removed {
+			err := unlinkEntry(j, r, st, sd, s.ignorePatterns(sd), opts.Restore)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := j.preserve(syncFile)
+	if err != nil {
+		return err
+	}
+	err = s.WriteToFile(syncFile)
+	if err != nil {
+		return err
+	}
+	for _, sd := range removed {
+		j.done(Action{Kind: ActionUpdateSyncFile, Destination: syncFile, Reason: "removed " + sd.Destination})
+	}
+	return nil
+}
+
+func matchesEntry(r *Resolver, sd SyncDefinition, src string, trg string) bool {
+	re := r.Resolve(sd)
+	if trg != "" {
+		abs, _ := filepath.Abs(trg)
+		if sd.Destination == trg || re.Destination == abs {
+			return true
+		}
+	}
+	if src != "" {
+		abs, _ := filepath.Abs(src)
+		if sd.Source == src || re.Source == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// unlinkEntry removes what sync put in destination of entry
+func unlinkEntry(j *Journal, r *Resolver, st *State, sd SyncDefinition, ignore []string, restore bool) error {
+	re := r.Resolve(sd)
+	if fi, err := os.Stat(re.Source); err == nil && fi.IsDir() && sd.Mirror {
+		return unlinkMirror(j, r, re, ignore, restore)
+	}
+	switch sd.LinkMode() {
+	case MODE_COPY:
+		return unlinkCopy(j, r, st, sd, re, restore)
+	case MODE_HARDLINK:
+		return unlinkHardlink(j, re, restore)
+	default:
+		return unlinkLink(j, r, re.Destination, restore)
+	}
+}
+
+// unlinkMirror removes links to files of mirrored directory, directories are kept
+func unlinkMirror(j *Journal, r *Resolver, re ResolvedEntry, ignore []string, restore bool) error {
+	return filepath.WalkDir(re.Source, func(p string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != re.Source && isIgnored(ignore, de.Name()) {
+			if de.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if de.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(re.Source, p)
+		return unlinkLink(j, r, filepath.Join(re.Destination, rel), restore)
+	})
+}
+
+// unlinkCopy removes copy which is still what sync wrote there.
+// Restored copy is kept as it already is a real file.
+func unlinkCopy(j *Journal, r *Resolver, st *State, sd SyncDefinition, re ResolvedEntry, restore bool) error {
+	// entry may be removed on machine it does not apply to
+	re.SkipReason = ""
+	es, err := entryStatus(r, sd, re, st)
+	if err != nil {
+		return err
+	}
+	switch {
+	case es.State == StateMissing:
+		return nil
+	case es.State != StateCopied && es.State != StatePermDrift:
+		return fmt.Errorf("(target = %s, state = %s) %w", re.Destination, es.State, ErrCannotUnlink)
+	case restore:
+		slog.Info("keeping copy", "target", re.Destination)
+		return nil
+	}
+
+	slog.Info("removing copy", "target", re.Destination)
+	err = j.remove(re.Destination)
+	if err != nil {
+		return err
+	}
+	j.done(Action{Kind: ActionUnlink, Source: re.Source, Destination: re.Destination})
+	return nil
+}
+
+// unlinkHardlink removes destination which is still the same file as source
+func unlinkHardlink(j *Journal, re ResolvedEntry, restore bool) error {
+	fi, err := os.Lstat(re.Destination)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	srcFi, err := os.Stat(re.Source)
+	if err != nil || !os.SameFile(srcFi, fi) {
+		return fmt.Errorf("(target = %s) %w", re.Destination, ErrCannotUnlink)
+	}
+
+	slog.Info("removing hardlink", "target", re.Destination)
+	err = j.remove(re.Destination)
+	if err != nil {
+		return err
+	}
+	j.done(Action{Kind: ActionUnlink, Source: re.Source, Destination: re.Destination})
+	if !restore {
+		return nil
+	}
+	return restoreFile(j, re.Source, re.Destination)
+}
+
+// unlinkLink removes destination link only if it points into repo
+func unlinkLink(j *Journal, r *Resolver, dest string, restore bool) error {
+	fi, err := os.Lstat(dest)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		slog.Warn("not removing, destination is not a symlink", "target", dest)
+		return nil
+	}
+
+	linkTarget, err := os.Readlink(dest)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(linkTarget) {
+		linkTarget = filepath.Join(filepath.Dir(dest), linkTarget)
+	}
+	if !isWithin(r.RepoDir, linkTarget) {
+		slog.Warn("not removing, link does not point into repo", "target", dest, "link", linkTarget)
+		return nil
+	}
+
+	slog.Info("removing link", "target", dest)
+	err = j.remove(dest)
+	if err != nil {
+		return err
+	}
+	j.done(Action{Kind: ActionUnlink, Source: linkTarget, Destination: dest})
+
+	if !restore {
+		return nil
+	}
+	return restoreFile(j, linkTarget, dest)
+}
+
+// restoreFile puts copy of repo file in place of removed link
+func restoreFile(j *Journal, src string, dest string) error {
+	slog.Info("restoring file", "source", src, "target", dest)
+	err := j.create(dest, func() error {
+		return copyPath(src, dest)
+	})
+	if err != nil {
+		return err
+	}
+	j.done(Action{Kind: ActionRestore, Source: src, Destination: dest})
+	return nil
+}
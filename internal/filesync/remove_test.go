@@ -0,0 +1,162 @@
+package filesync
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRemoveFromSyncFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := path.Join(tmpDir, "home")
+	repo := path.Join(tmpDir, "repo")
+	_ = os.MkdirAll(home, 0755)
+	_ = os.MkdirAll(repo, 0755)
+	t.Setenv("HOME", home)
+	conf := &confMock{path.Join(repo, SYNC_FILE_NAME)}
+
+	kept := path.Join(home, ".kept")
+	restored := path.Join(home, ".restored")
+	foreign := path.Join(home, ".foreign")
+	for _, p := range []string{kept, restored} {
+		_ = os.WriteFile(p, []byte(p), 0644)
+		if _, err := Adopt(conf, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.Symlink(path.Join(tmpDir, "elsewhere"), foreign)
+	if err := MaybeCreateAndUpdateSyncFile(conf, ".foreign", foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RemoveFromSyncFile(conf, "", restored, RemoveOptions{Restore: true}); err != nil {
+		t.Fatalf("RemoveFromSyncFile() failed: %v", err)
+	}
+	fi, err := os.Lstat(restored)
+	if err != nil || fi.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("restored target is not a regular file (err: %v)", err)
+	}
+	if d, _ := os.ReadFile(restored); string(d) != restored {
+		t.Errorf("restored content = %q", d)
+	}
+
+	if _, err := RemoveFromSyncFile(conf, ".foreign", "", RemoveOptions{Unlink: true}); err != nil {
+		t.Fatalf("RemoveFromSyncFile() failed: %v", err)
+	}
+	if _, err := os.Lstat(foreign); err != nil {
+		t.Errorf("link pointing outside repo was removed: %v", err)
+	}
+
+	_, err = RemoveFromSyncFile(conf, "", restored, RemoveOptions{})
+	if !errors.Is(err, ErrNoMatchingEntry) {
+		t.Errorf("removing missing entry returned %v, want %v", err, ErrNoMatchingEntry)
+	}
+
+	d, _ := os.ReadFile(conf.GetSyncFilePath())
+	s, _ := ReadSchema(d)
+	if len(s.Entries) != 1 || s.Entries[0].Destination != "~/.kept" {
+		t.Errorf("sync file entries = %+v, want only %s", s.Entries, kept)
+	}
+}
+
+func TestRemoveFromSyncFile_Vars(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := path.Join(tmpDir, "home")
+	repo := path.Join(tmpDir, "repo")
+	dest := path.Join(home, ".config", "app", "app.conf")
+	_ = os.MkdirAll(path.Dir(dest), 0755)
+	_ = os.MkdirAll(repo, 0755)
+	t.Setenv("HOME", home)
+	conf := &confVarsMock{
+		confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")},
+		map[string]string{"app_dir": path.Join(home, ".config", "app")},
+	}
+	_ = os.WriteFile(path.Join(repo, "app.conf"), []byte("app"), 0644)
+	_ = os.WriteFile(conf.GetSyncFilePath(), []byte("version: 2\nentries:\n  - src: app.conf\n    dest: ${app_dir}/app.conf\n"), 0644)
+	_ = os.Symlink(path.Join(repo, "app.conf"), dest)
+
+	removed, err := RemoveFromSyncFile(conf, "", dest, RemoveOptions{Unlink: true})
+	if err != nil {
+		t.Fatalf("RemoveFromSyncFile() failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Destination != "${app_dir}/app.conf" {
+		t.Errorf("removed = %+v, want entry with var based dest", removed)
+	}
+	if _, err := os.Lstat(dest); !os.IsNotExist(err) {
+		t.Errorf("link at var based dest was not removed: %v", err)
+	}
+}
+
+func TestRemoveFromSyncFile_Modes(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := path.Join(tmpDir, "home")
+	repo := path.Join(tmpDir, "repo")
+	_ = os.MkdirAll(home, 0755)
+	_ = os.MkdirAll(path.Join(repo, "nvim", "lua"), 0755)
+	t.Setenv("HOME", home)
+	for _, f := range []string{"token", "hard", "app", "nvim/init.lua", "nvim/lua/opts.lua", "nvim/README.md"} {
+		_ = os.WriteFile(path.Join(repo, f), []byte(f), 0644)
+	}
+	conf := &confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")}
+	s := &Schema{
+		Version:  SCHEMA_VERSION,
+		Packages: []Package{{Name: "nvim", Target: "~/.config/nvim"}},
+		Entries: []SyncDefinition{
+			{Source: "token", Destination: "~/.token", Mode: MODE_COPY},
+			{Source: "hard", Destination: "~/.hard", Mode: MODE_HARDLINK},
+			{Source: "app", Destination: "~/.app", Mode: MODE_COPY},
+		},
+	}
+	if err := s.WriteToFile(conf.GetSyncFilePath()); err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	// changed copy is not removed and entry stays
+	_ = os.WriteFile(path.Join(home, ".app"), []byte("local"), 0644)
+	if _, err := RemoveFromSyncFile(conf, "", path.Join(home, ".app"), RemoveOptions{Unlink: true}); !errors.Is(err, ErrCannotUnlink) {
+		t.Errorf("removing changed copy returned %v, want %v", err, ErrCannotUnlink)
+	}
+	if d, _ := os.ReadFile(path.Join(home, ".app")); string(d) != "local" {
+		t.Errorf("changed copy = %q, want it kept", d)
+	}
+
+	for _, src := range []string{"nvim", "token"} {
+		if _, err := RemoveFromSyncFile(conf, src, "", RemoveOptions{Unlink: true}); err != nil {
+			t.Fatalf("RemoveFromSyncFile(%s) failed: %v", src, err)
+		}
+	}
+	for _, f := range []string{".token", ".config/nvim/init.lua", ".config/nvim/lua/opts.lua"} {
+		if _, err := os.Lstat(path.Join(home, f)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", f, err)
+		}
+	}
+	if fi, err := os.Stat(path.Join(home, ".config/nvim/lua")); err != nil || !fi.IsDir() {
+		t.Errorf("directory of mirrored package was removed: %v", err)
+	}
+
+	if _, err := RemoveFromSyncFile(conf, "", "~/.hard", RemoveOptions{Restore: true}); err != nil {
+		t.Fatalf("RemoveFromSyncFile(.hard) failed: %v", err)
+	}
+	fi, err := os.Stat(path.Join(home, ".hard"))
+	srcFi, _ := os.Stat(path.Join(repo, "hard"))
+	if err != nil || os.SameFile(fi, srcFi) {
+		t.Errorf("restored hardlink is not a separate file (err: %v)", err)
+	}
+	if d, _ := os.ReadFile(path.Join(home, ".hard")); string(d) != "hard" {
+		t.Errorf("restored hardlink content = %q", d)
+	}
+
+	d, _ := os.ReadFile(conf.GetSyncFilePath())
+	s, _ = ReadSchema(d)
+	if len(s.Packages) != 0 || len(s.Entries) != 1 || s.Entries[0].Source != "app" {
+		t.Errorf("sync file = %+v, want only app entry", s)
+	}
+}
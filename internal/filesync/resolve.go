@@ -0,0 +1,138 @@
+package filesync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolver turns paths from sync definitions into absolute paths on this machine.
+// Paths are expanded first (see ExpandPath) with variables taking precedence over
+// environment, then relative sources are resolved against repo dir (directory of
+// sync file) and relative destinations against home dir.
+type Resolver struct {
+	RepoDir string
+	// SyncFile entries come from
+	SyncFile string
+	HomeDir  string
+	Lookup   LookupFunc
+	// Facts are used to check entry conditions
+	Facts Facts
+	// StateDir keeps state between runs, state is not persisted when empty
+	StateDir string
+	// Vars are used in paths and to render templated sources
+	Vars map[string]string
+	// IdentityFile holds key decrypting encrypted sources
+	IdentityFile string
+}
+
+func NewResolver(conf syncFileGetter) *Resolver {
+	return &Resolver{
+		RepoDir:      repoDir(conf),
+		SyncFile:     syncFilePath(conf),
+		HomeDir:      os.Getenv("HOME"),
+		Lookup:       os.LookupEnv,
+		Facts:        CurrentFacts(),
+		StateDir:     stateDir(conf),
+		IdentityFile: identityFile(conf),
+	}
+}
+
+// repoDir returns directory containing sync file
+func repoDir(conf syncFileGetter) string {
+	syncFile := conf.GetSyncFilePath()
+	if syncFile == "" {
+		cwd, _ := os.Getwd()
+		return cwd
+	}
+	return filepath.Dir(syncFile)
+}
+
+// isWithin checks if p is equal to dir or lies under it
+func isWithin(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func resolveAgainst(base, p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(base, p)
+}
+
+// lookup returns variable or environment variable when there is no variable with the name
+func (r *Resolver) lookup(key string) (string, bool) {
+	if val, ok := r.Vars[key]; ok {
+		return val, true
+	}
+	return r.env(key)
+}
+
+func (r *Resolver) env(key string) (string, bool) {
+	if r.Lookup == nil {
+		return os.LookupEnv(key)
+	}
+	return r.Lookup(key)
+}
+
+func (r *Resolver) expand(p string) string {
+	return ExpandPath(p, r.HomeDir, r.lookup)
+}
+
+// Source returns absolute path of the file link should point to
+func (r *Resolver) Source(p string) string {
+	return resolveAgainst(r.RepoDir, r.expand(p))
+}
+
+// Destination returns absolute path where link should be created
+func (r *Resolver) Destination(p string) string {
+	return resolveAgainst(r.HomeDir, r.expand(p))
+}
+
+// Resolve returns absolute paths of the entry. When entry does not apply
+// to this machine SkipReason explains why.
+func (r *Resolver) Resolve(sd SyncDefinition) ResolvedEntry {
+	_, reason := sd.When.Check(r.Facts, r.env)
+	return ResolvedEntry{
+		Source:      r.Source(sd.Source),
+		Destination: r.Destination(sd.Destination),
+		SkipReason:  reason,
+	}
+}
+
+// PortablePath turns path given by user relative to cwd into absolute path
+// with home dir replaced by `~`
+func (r *Resolver) PortablePath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return portablePath(abs, r.HomeDir), nil
+}
+
+// FromCommandLine builds definition out of paths given by user which are relative to cwd.
+// Source inside repo is stored relative to repo dir and paths under home dir
+// are stored with `~` so sync file stays portable between machines.
+func (r *Resolver) FromCommandLine(src string, trg string) (SyncDefinition, error) {
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return SyncDefinition{}, err
+	}
+	absTrg, err := filepath.Abs(trg)
+	if err != nil {
+		return SyncDefinition{}, err
+	}
+
+	sd := SyncDefinition{
+		Source:      portablePath(absSrc, r.HomeDir),
+		Destination: portablePath(absTrg, r.HomeDir),
+	}
+	if isWithin(r.RepoDir, absSrc) {
+		sd.Source, _ = filepath.Rel(r.RepoDir, absSrc)
+	}
+	return sd, nil
+}
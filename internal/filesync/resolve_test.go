@@ -0,0 +1,119 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	t.Setenv("FTUCK_TEST_DIR", "dir")
+	r := &Resolver{
+		RepoDir: "/repo",
+		HomeDir: "/home/user",
+	}
+
+	tests := []struct {
+		name string // description of this test case
+		def  SyncDefinition
+		want ResolvedEntry
+	}{
+		{
+			name: "relative source is resolved against repo dir",
+			def:  SyncDefinition{Source: "nvim/init.lua", Destination: "/etc/init.lua"},
+			want: ResolvedEntry{Source: "/repo/nvim/init.lua", Destination: "/etc/init.lua"},
+		},
+		{
+			name: "absolute source is not changed",
+			def:  SyncDefinition{Source: "/other/file", Destination: "/etc/file"},
+			want: ResolvedEntry{Source: "/other/file", Destination: "/etc/file"},
+		},
+		{
+			name: "relative destination is resolved against home dir",
+			def:  SyncDefinition{Source: "bashrc", Destination: ".bashrc"},
+			want: ResolvedEntry{Source: "/repo/bashrc", Destination: "/home/user/.bashrc"},
+		},
+		{
+			name: "tilde and variables are expanded",
+			def:  SyncDefinition{Source: "$FTUCK_TEST_DIR/file", Destination: "~/.file"},
+			want: ResolvedEntry{Source: "/repo/dir/file", Destination: "/home/user/.file"},
+		},
+		{
+			name: "paths are cleaned",
+			def:  SyncDefinition{Source: "./a/../b", Destination: "/etc//b/"},
+			want: ResolvedEntry{Source: "/repo/b", Destination: "/etc/b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Resolve(tt.def)
+			if got != tt.want {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+
+	r := NewResolver(&confMock{"/repo/" + SYNC_FILE_NAME})
+	if r.RepoDir != "/repo" {
+		t.Errorf("RepoDir = %s, want /repo", r.RepoDir)
+	}
+	if r.HomeDir != "/home/user" {
+		t.Errorf("HomeDir = %s, want /home/user", r.HomeDir)
+	}
+
+	cwd, _ := os.Getwd()
+	r = NewResolver(&confMock{""})
+	if r.RepoDir != cwd {
+		t.Errorf("RepoDir without sync file = %s, want %s", r.RepoDir, cwd)
+	}
+}
+
+func TestResolver_FromCommandLine(t *testing.T) {
+	cwd, _ := os.Getwd()
+	r := &Resolver{
+		RepoDir: path.Dir(cwd),
+		HomeDir: "/home/user",
+	}
+
+	tests := []struct {
+		name string // description of this test case
+		src  string
+		trg  string
+		want SyncDefinition
+	}{
+		{
+			name: "source inside repo is stored relative",
+			src:  "file",
+			trg:  "/home/user/.file",
+			want: SyncDefinition{Source: path.Join(path.Base(cwd), "file"), Destination: "~/.file"},
+		},
+		{
+			name: "source outside repo stays absolute",
+			src:  "/outside/file",
+			trg:  "/home/user/.file",
+			want: SyncDefinition{Source: "/outside/file", Destination: "~/.file"},
+		},
+		{
+			name: "relative target is taken from cwd",
+			src:  "/outside/file",
+			trg:  "target",
+			want: SyncDefinition{Source: "/outside/file", Destination: path.Join(cwd, "target")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.FromCommandLine(tt.src, tt.trg)
+			if err != nil {
+				t.Fatalf("FromCommandLine() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromCommandLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
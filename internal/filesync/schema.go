@@ -2,50 +2,201 @@ package filesync
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 
 	"gopkg.in/yaml.v3"
 )
 
+// SCHEMA_VERSION is written to every saved sync file.
+// Version 1 is a bare list of sync definitions.
+const SCHEMA_VERSION int = 2
+
+// SYNC_FILE_PERM is used when sync file is created, existing file keeps its permissions
+const SYNC_FILE_PERM os.FileMode = 0644
+
+var ErrUnsupportedVersion = errors.New("unsupported sync file version, update ftuck")
+
 type SyncDefinition struct {
-	Source      string `yaml:"src"`
-	Destination string `yaml:"dest"`
+	Source      string     `yaml:"src"`
+	Destination string     `yaml:"dest"`
+	When        *Condition `yaml:"when,omitempty"`
+	// Profiles entry belongs to. Entry without profiles is always synced.
+	Profiles []string `yaml:"profiles,omitempty"`
+	// Mirror creates real directories in destination and links only files from source directory
+	Mirror bool `yaml:"mirror,omitempty"`
+	// Ignore patterns of file names left out when mirroring
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Mode is one of MODE_SYMLINK (default), MODE_COPY or MODE_HARDLINK.
+	// Mirrored entries are always symlinked.
+	Mode string `yaml:"mode,omitempty"`
+	// Template renders source with text/template, templated entries are always copied
+	Template bool `yaml:"template,omitempty"`
+	// Encrypted source is decrypted into destination readable only by the owner,
+	// encrypted entries are always copied
+	Encrypted bool `yaml:"encrypted,omitempty"`
+	// Perm are octal permissions enforced on source of links or on copied destination
+	Perm string `yaml:"perm,omitempty"`
+	// Owner and Group are user and group names or ids enforced on the same path as Perm
+	Owner string `yaml:"owner,omitempty"`
+	Group string `yaml:"group,omitempty"`
+	// DirPerm are octal permissions of missing parent directories created for destination
+	DirPerm string `yaml:"dir_perm,omitempty"`
+	// Hooks run around actions of this entry
+	Hooks *Hooks `yaml:"hooks,omitempty"`
+}
+
+// Modes of putting source in destination
+const (
+	MODE_SYMLINK  string = "symlink"
+	MODE_COPY     string = "copy"
+	MODE_HARDLINK string = "hardlink"
+)
+
+// LinkMode returns entry mode with default applied
+func (sd SyncDefinition) LinkMode() string {
+	if sd.Template || sd.Encrypted {
+		return MODE_COPY
+	}
+	if sd.Mode == "" {
+		return MODE_SYMLINK
+	}
+	return sd.Mode
 }
 
-type Schema []SyncDefinition
+// Settings are sync file wide options
+type Settings struct {
+	// DefaultProfiles are activated when neither command line nor configuration selects any
+	DefaultProfiles []string `yaml:"default_profiles,omitempty"`
+	// Ignore patterns of file names left out when mirroring, DEFAULT_IGNORE when not set
+	Ignore []string `yaml:"ignore,omitempty"`
+	// DirPerm are octal permissions of directories created by sync, DEFAULT_DIR_PERM when not set
+	DirPerm string `yaml:"dir_perm,omitempty"`
+	// HookTimeout limits every hook, DEFAULT_HOOK_TIMEOUT when not set
+	HookTimeout string `yaml:"hook_timeout,omitempty"`
+}
+
+// Schema is the content of sync file
+type Schema struct {
+	Version  int      `yaml:"version"`
+	Settings Settings `yaml:"settings,omitempty"`
+	// Vars are template variables, configuration can override them per machine
+	Vars     map[string]string  `yaml:"vars,omitempty"`
+	Hooks    Hooks              `yaml:"hooks,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	Packages []Package          `yaml:"packages,omitempty"`
+	Entries  []SyncDefinition   `yaml:"entries"`
 
+	// active profiles selected with SelectProfiles, nil means all entries are synced
+	active map[string]bool
+}
+
+// WriteToFile saves entries and packages of s. Existing file is edited on yaml node level
+// so its version, comments and untouched definitions stay as they are. Version 1 file
+// is migrated only when packages have to be added to it.
 func (s *Schema) WriteToFile(path string) error {
-	d, err := yaml.Marshal(s)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	doc := yaml.Node{}
+	err = yaml.Unmarshal(data, &doc)
 	if err != nil {
 		return err
 	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{keyNode("version"), versionNode()}}},
+		}
+	}
 
-	err = os.WriteFile(path, d, 0644)
+	root := doc.Content[0]
+	if root.Kind == yaml.SequenceNode && len(s.Packages) > 0 {
+		root = migrateBareList(root)
+		doc.Content[0] = root
+	}
+	switch root.Kind {
+	case yaml.SequenceNode:
+		err = updateList(root, s.Entries)
+	case yaml.MappingNode:
+		err = updateList(listValue(root, "entries"), s.Entries)
+		if err == nil && (len(s.Packages) > 0 || mappingValue(root, "packages") != nil) {
+			err = updateList(listValue(root, "packages"), s.Packages)
+		}
+	default:
+		err = fmt.Errorf("(line %d) sync file must be a list or a mapping", root.Line)
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	d, err := encodeDocument(&doc)
+	if err != nil {
+		return err
+	}
+	return WriteSyncFile(path, d)
+}
+
+// WriteSyncFile replaces sync file content atomically, so interrupted write
+// never leaves it half written. Existing file keeps its permissions.
+func WriteSyncFile(path string, d []byte) error {
+	return writeFileAtomic(path, d, SYNC_FILE_PERM)
 }
 
 func (s *Schema) Append(definition SyncDefinition) {
-	*s = append(*s, definition)
+	s.Entries = append(s.Entries, definition)
+}
+
+// Remove deletes all definitions and packages matching given func and returns them,
+// packages are matched and returned as their definitions
+func (s *Schema) Remove(match func(SyncDefinition) bool) []SyncDefinition {
+	removed := []SyncDefinition{}
+	kept := []SyncDefinition{}
+	for _, sd := range s.Entries {
+		if match(sd) {
+			removed = append(removed, sd)
+			continue
+		}
+		kept = append(kept, sd)
+	}
+	s.Entries = kept
+
+	keptPackages := []Package{}
+	for _, p := range s.Packages {
+		if match(p.Definition()) {
+			removed = append(removed, p.Definition())
+			continue
+		}
+		keptPackages = append(keptPackages, p)
+	}
+	s.Packages = keptPackages
+	return removed
 }
 
+// ForEach calls f for every entry and then for definitions of every package
 func (s *Schema) ForEach(f func(SyncDefinition) error) error {
-	for _, sd := range *s {
+	for _, sd := range s.Entries {
 		err := f(sd)
 		if err != nil {
 			return err
 		}
 	}
+	for _, p := range s.Packages {
+		err := f(p.Definition())
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func ReadOrCreate(path string) ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, SYNC_FILE_PERM)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -54,11 +205,32 @@ func ReadOrCreate(path string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// ReadSchema parses sync file. Files written before versioning
+// (bare list of definitions) are read as well.
 func ReadSchema(data []byte) (*Schema, error) {
+	doc := yaml.Node{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
 	res := Schema{}
-	err := yaml.Unmarshal(data, &res)
+	if len(doc.Content) == 0 {
+		return &res, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind == yaml.SequenceNode {
+		res.Version = 1
+		err = root.Decode(&res.Entries)
+	} else {
+		err = root.Decode(&res)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if res.Version > SCHEMA_VERSION {
+		return nil, fmt.Errorf("(version = %d) %w", res.Version, ErrUnsupportedVersion)
+	}
 	return &res, nil
 }
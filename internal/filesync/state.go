@@ -0,0 +1,151 @@
+package filesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const STATE_FILE_NAME string = "state.yaml"
+
+type stateDirGetter interface {
+	GetStateDir() string
+}
+
+// State is what ftuck remembers about this machine between runs
+type State struct {
+	// Checksums of copied destinations recorded at the time of copying
+	Checksums map[string]string `yaml:"checksums,omitempty"`
+	// Links created by ftuck, destination to link target. Only these are ever pruned.
+	Links map[string]string `yaml:"links,omitempty"`
+
+	// path is empty for state which is not persisted
+	path  string
+	dirty bool
+}
+
+// stateDir returns state directory from configuration, empty when configuration does not provide it
+func stateDir(conf syncFileGetter) string {
+	sg, ok := conf.(stateDirGetter)
+	if !ok {
+		return ""
+	}
+	return sg.GetStateDir()
+}
+
+// LoadState reads state from given directory. Empty dir gives in memory state.
+func LoadState(dir string) (*State, error) {
+	st := &State{
+		Checksums: map[string]string{},
+		Links:     map[string]string{},
+	}
+	if dir == "" {
+		return st, nil
+	}
+	st.path = filepath.Join(dir, STATE_FILE_NAME)
+
+	d, err := os.ReadFile(st.path)
+	if err != nil && os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(d, st)
+	if err != nil {
+		return nil, err
+	}
+	if st.Checksums == nil {
+		st.Checksums = map[string]string{}
+	}
+	if st.Links == nil {
+		st.Links = map[string]string{}
+	}
+	return st, nil
+}
+
+func (st *State) SetChecksum(dest string, sum string) {
+	st.Checksums[dest] = sum
+	st.dirty = true
+}
+
+func (st *State) DeleteChecksum(dest string) {
+	delete(st.Checksums, dest)
+	st.dirty = true
+}
+
+func (st *State) SetLink(dest string, target string) {
+	st.Links[dest] = target
+	st.dirty = true
+}
+
+func (st *State) DeleteLink(dest string) {
+	delete(st.Links, dest)
+	st.dirty = true
+}
+
+// Save writes state if it was changed and is persisted
+func (st *State) Save() error {
+	if st.path == "" || !st.dirty {
+		return nil
+	}
+	d, err := yaml.Marshal(st)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(st.path), 0755)
+	if err != nil {
+		return err
+	}
+	// state keeps checksums of secrets and paths of home, keep it private
+	err = os.WriteFile(st.path, d, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(st.path, 0600)
+	if err != nil {
+		return err
+	}
+	st.dirty = false
+	return nil
+}
+
+// Checksum returns sha256 of file content or of all files in directory tree
+func Checksum(p string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(p, func(fp string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(p, fp)
+		io.WriteString(h, rel+"\x00")
+		if de.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(fp)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, target+"\x00")
+			return nil
+		}
+		if de.IsDir() {
+			return nil
+		}
+		f, err := os.Open(fp)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,258 @@
+package filesync
+
+import (
+	"fmt"
+	"os"
+)
+
+type EntryState string
+
+const (
+	StateLinked        EntryState = "linked"
+	StateCopied        EntryState = "copied"
+	StateMissing       EntryState = "missing"
+	StateElsewhere     EntryState = "pointing-elsewhere"
+	StateBlocked       EntryState = "blocked-by-file"
+	StateSourceMissing EntryState = "source-missing"
+	StateSkipped       EntryState = "skipped"
+	StateDrifted       EntryState = "drifted"
+	StatePermDrift     EntryState = "wrong-permissions"
+)
+
+// Drift tells which side of copied entry changed since it was copied
+type Drift string
+
+const (
+	DriftSource      Drift = "source changed"
+	DriftDestination Drift = "destination changed"
+	DriftBoth        Drift = "source and destination changed"
+)
+
+// EntryStatus describes how a single sync definition looks on disk right now
+type EntryStatus struct {
+	Definition  SyncDefinition
+	Source      string
+	Destination string
+	State       EntryState
+	// LinkTarget is set when destination is a symlink
+	LinkTarget string
+	// Reason explains why entry was skipped or blocked
+	Reason string
+	// Drift is set for drifted copies
+	Drift Drift
+	// Checksum of source, set for copied entries.
+	// For templates and encrypted entries it is checksum of content written to destination,
+	// keyed with identity for encrypted ones.
+	Checksum string
+
+	// unsupported is set when entry cannot be deployed in its mode at all
+	unsupported bool
+	// content written to destination of encrypted or templated entry
+	content []byte
+	// perm of destination written from content
+	perm os.FileMode
+}
+
+// InSync is true when entry is linked, copied or does not apply to this machine
+func (es EntryStatus) InSync() bool {
+	return es.State == StateLinked || es.State == StateCopied || es.State == StateSkipped
+}
+
+// Status inspects every entry without changing anything on disk.
+// Paths are resolved the same way as in SyncAllEntries.
+func (s *Schema) Status(conf syncFileGetter) ([]EntryStatus, error) {
+	r, err := s.newResolver(conf)
+	if err != nil {
+		return nil, err
+	}
+	st, err := LoadState(r.StateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []EntryStatus{}
+	err = s.ForEach(func(sd SyncDefinition) error {
+		es, err := entryStatus(r, sd, s.resolve(r, sd), st)
+		if err != nil {
+			return err
+		}
+		if sd.Mirror && es.State != StateSkipped && es.State != StateSourceMissing {
+			if fi, err := os.Stat(es.Source); err == nil && fi.IsDir() {
+				es, err = mirrorStatus(r.RepoDir, es, s.ignorePatterns(sd))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		res = append(res, es)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func entryStatus(r *Resolver, sd SyncDefinition, re ResolvedEntry, st *State) (EntryStatus, error) {
+	es := EntryStatus{
+		Definition:  sd,
+		Source:      re.Source,
+		Destination: re.Destination,
+	}
+
+	if re.SkipReason != "" {
+		es.State = StateSkipped
+		es.Reason = re.SkipReason
+		return es, nil
+	}
+
+	srcFi, err := os.Stat(es.Source)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return es, err
+		}
+		es.State = StateSourceMissing
+		return es, nil
+	}
+
+	if reason := unsupportedReason(sd, srcFi); reason != "" {
+		es.State = StateBlocked
+		es.Reason = reason
+		es.unsupported = true
+		return es, nil
+	}
+	if _, _, err := sd.FileOwner(); err != nil {
+		es.State = StateBlocked
+		es.Reason = err.Error()
+		es.unsupported = true
+		return es, nil
+	}
+
+	if sd.Template || sd.Encrypted {
+		es = contentStatus(r, es, srcFi)
+		if es.unsupported {
+			return es, nil
+		}
+	}
+
+	fi, err := os.Lstat(es.Destination)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return es, err
+		}
+		es.State = StateMissing
+		return es, nil
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		es.LinkTarget, err = os.Readlink(es.Destination)
+		if err != nil {
+			return es, err
+		}
+	}
+
+	switch sd.LinkMode() {
+	case MODE_SYMLINK:
+		es = symlinkStatus(es, fi)
+	case MODE_COPY:
+		es, err = copyStatus(r, es, fi, st)
+		if err != nil {
+			return es, err
+		}
+	default:
+		es = hardlinkStatus(es, srcFi, fi)
+	}
+	return permStatus(es)
+}
+
+// unsupportedReason explains why source cannot be deployed in entry mode
+func unsupportedReason(sd SyncDefinition, srcFi os.FileInfo) string {
+	switch sd.LinkMode() {
+	case MODE_SYMLINK, MODE_COPY:
+		if sd.Template && srcFi.IsDir() {
+			return "only files can be templates"
+		}
+		if sd.Encrypted && srcFi.IsDir() {
+			return "only files can be encrypted"
+		}
+		return ""
+	case MODE_HARDLINK:
+		if srcFi.IsDir() {
+			return "directories cannot be hardlinked"
+		}
+		return ""
+	default:
+		return fmt.Sprintf("unknown mode %s", sd.Mode)
+	}
+}
+
+func symlinkStatus(es EntryStatus, fi os.FileInfo) EntryStatus {
+	switch {
+	case fi.Mode()&os.ModeSymlink == 0:
+		es.State = StateBlocked
+	case es.LinkTarget != es.Source:
+		es.State = StateElsewhere
+	default:
+		es.State = StateLinked
+	}
+	return es
+}
+
+func hardlinkStatus(es EntryStatus, srcFi os.FileInfo, fi os.FileInfo) EntryStatus {
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		es.State = StateElsewhere
+	case os.SameFile(srcFi, fi):
+		es.State = StateLinked
+	default:
+		es.State = StateBlocked
+	}
+	return es
+}
+
+// copyStatus compares checksums of source, destination and the one recorded
+// when destination was copied to find out which side changed
+func copyStatus(r *Resolver, es EntryStatus, fi os.FileInfo, st *State) (EntryStatus, error) {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		es.State = StateElsewhere
+		return es, nil
+	}
+
+	var err error
+	// checksum of content is set by contentStatus
+	if es.content == nil {
+		es.Checksum, err = Checksum(es.Source)
+		if err != nil {
+			return es, err
+		}
+	}
+	destSum, err := destinationChecksum(r, es, fi)
+	if err != nil {
+		return es, err
+	}
+	recorded := st.Checksums[es.Destination]
+
+	switch {
+	case es.Checksum == destSum:
+		es.State = StateCopied
+		return es, nil
+	case recorded == "":
+		es.State = StateBlocked
+		es.Reason = "destination differs from source"
+		if es.Definition.Template {
+			es.Reason = "destination differs from rendered template"
+		} else if es.Definition.Encrypted {
+			es.Reason = "destination differs from decrypted source"
+		}
+		return es, nil
+	case destSum == recorded:
+		es.Drift = DriftSource
+	case es.Checksum == recorded:
+		es.Drift = DriftDestination
+	default:
+		es.Drift = DriftBoth
+	}
+	es.State = StateDrifted
+	es.Reason = string(es.Drift)
+	return es, nil
+}
@@ -0,0 +1,80 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSchema_Status(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcPath := path.Join(tmpDir, "src")
+	destPath := path.Join(tmpDir, "dest")
+	_ = os.MkdirAll(srcPath, 0755)
+	_ = os.MkdirAll(destPath, 0755)
+
+	srcF1Name := path.Join(srcPath, "file1")
+	_ = os.WriteFile(srcF1Name, []byte("file1"), 0644)
+	otherName := path.Join(srcPath, "other")
+	_ = os.WriteFile(otherName, []byte("other"), 0644)
+
+	_ = os.Symlink(srcF1Name, path.Join(destPath, "linked"))
+	_ = os.Symlink(otherName, path.Join(destPath, "elsewhere"))
+	_ = os.WriteFile(path.Join(destPath, "blocked"), []byte("blocked"), 0644)
+
+	tests := []struct {
+		name string // description of this test case
+		def  SyncDefinition
+		want EntryState
+	}{
+		{
+			name: "correct link",
+			def:  SyncDefinition{Source: "file1", Destination: path.Join(destPath, "linked")},
+			want: StateLinked,
+		},
+		{
+			name: "missing destination",
+			def:  SyncDefinition{Source: "file1", Destination: path.Join(destPath, "missing")},
+			want: StateMissing,
+		},
+		{
+			name: "link to different file",
+			def:  SyncDefinition{Source: "file1", Destination: path.Join(destPath, "elsewhere")},
+			want: StateElsewhere,
+		},
+		{
+			name: "regular file in place of link",
+			def:  SyncDefinition{Source: "file1", Destination: path.Join(destPath, "blocked")},
+			want: StateBlocked,
+		},
+		{
+			name: "source does not exist",
+			def:  SyncDefinition{Source: "nope", Destination: path.Join(destPath, "linked")},
+			want: StateSourceMissing,
+		},
+		{
+			name: "entry for other os",
+			def: SyncDefinition{
+				Source:      "nope",
+				Destination: path.Join(destPath, "missing"),
+				When:        &Condition{OS: []string{"plan9-only"}},
+			},
+			want: StateSkipped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Schema{Entries: []SyncDefinition{tt.def}}
+			got, err := s.Status(&confMock{path.Join(srcPath, SYNC_FILE_NAME)})
+			if err != nil {
+				t.Fatalf("Status() failed: %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("Status() returned %d entries, want 1", len(got))
+			}
+			if got[0].State != tt.want {
+				t.Errorf("Status() state = %s, want %s", got[0].State, tt.want)
+			}
+		})
+	}
+}
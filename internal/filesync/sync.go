@@ -2,10 +2,8 @@ package filesync
 
 import (
 	"fmt"
-	"log/slog"
 	"os"
 	"path"
-	"path/filepath"
 )
 
 type syncFileGetter interface {
@@ -13,12 +11,7 @@ type syncFileGetter interface {
 }
 
 func MaybeCreateAndUpdateSyncFile(conf syncFileGetter, src string, trg string) error {
-	syncFile := conf.GetSyncFilePath()
-	// return error if sync file not set
-	if syncFile == "" {
-		cwd, _ := os.Getwd()
-		syncFile = path.Join(cwd, SYNC_FILE_NAME)
-	}
+	syncFile := syncFilePath(conf)
 
 	// read sync definitions
 	d, err := ReadOrCreate(syncFile)
@@ -40,51 +33,50 @@ func MaybeCreateAndUpdateSyncFile(conf syncFileGetter, src string, trg string) e
 	return s.WriteToFile(syncFile)
 }
 
+// syncFilePath returns sync file from configuration, one in working directory when it is not set
+func syncFilePath(conf syncFileGetter) string {
+	syncFile := conf.GetSyncFilePath()
+	if syncFile == "" {
+		cwd, _ := os.Getwd()
+		syncFile = path.Join(cwd, SYNC_FILE_NAME)
+	}
+	return syncFile
+}
+
+// SyncAllEntries plans and executes sync of every entry in schema
 func (s *Schema) SyncAllEntries(conf syncFileGetter) error {
-	return s.ForEach(func(sd SyncDefinition) error {
-		source := sd.Source
-		if !filepath.IsAbs(source) {
-			source = filepath.Join(conf.GetSyncFilePath(), source)
-		}
-		fi, err := os.Lstat(sd.Destination)
-		if err != nil && !os.IsNotExist(err) {
-			slog.Error("syncing", "error", err, "target", sd.Destination)
-			return err
-		}
-		// Creating link if it does not exist
-		if err != nil && os.IsNotExist(err) {
-			slog.Info("creating link", "source", source, "target", sd.Destination)
-			os.Symlink(source, sd.Destination)
-			return nil
-		}
-		// ommiting if file exists
-		if fi.Mode()&os.ModeSymlink == 1 {
-			err := fmt.Errorf("(path = %s) file exists", sd.Destination)
-			slog.Error("target file already exists and is not a Symlink", "error", err)
-			return nil
-		}
-		existingSource, err := os.Readlink(sd.Destination)
-		if err != nil {
-			slog.Error("reading link", "error", err)
-			return err
-		}
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		return err
+	}
+	return p.Execute()
+}
 
-		// updating if source is different
-		if existingSource != source {
-			slog.Error("link different", "source", source, "link", existingSource)
-			err := os.Remove(sd.Destination)
-			if err != nil {
-				return err
-			}
-			err = os.Symlink(source, sd.Destination)
-			if err != nil {
-				return err
-			}
-			return nil
-		}
+// ResolvedEntry is sync definition with paths resolved the way sync uses them
+type ResolvedEntry struct {
+	Source      string `json:"source" yaml:"source"`
+	Destination string `json:"destination" yaml:"destination"`
+	SkipReason  string `json:"skip_reason,omitempty" yaml:"skip_reason,omitempty"`
+}
 
-		// nothing to be done here
-		slog.Info("nothing to do", "target", sd.Destination)
+// resolve resolves entry paths and checks if entry applies to this machine and active profiles
+func (s *Schema) resolve(r *Resolver, sd SyncDefinition) ResolvedEntry {
+	re := r.Resolve(sd)
+	if re.SkipReason == "" {
+		re.SkipReason = s.profileSkipReason(sd)
+	}
+	return re
+}
+
+func (s *Schema) Resolve(conf syncFileGetter) ([]ResolvedEntry, error) {
+	r, err := s.newResolver(conf)
+	if err != nil {
+		return nil, err
+	}
+	res := []ResolvedEntry{}
+	s.ForEach(func(sd SyncDefinition) error {
+		res = append(res, s.resolve(r, sd))
 		return nil
 	})
+	return res, nil
 }
@@ -45,15 +45,14 @@ func (c *confMock) GetSyncFilePath() string {
 }
 
 func TestSchema_SyncAllEntries(t *testing.T) {
-	// os.CreateTemp(dir string, pattern string)
-	tmpDir := os.TempDir()
+	tmpDir := t.TempDir()
 	const testDir = "test_sync_all"
 	srcPath := path.Join(tmpDir, testDir, "src")
 	destPath := path.Join(tmpDir, testDir, "dest")
 
 	// Create src and dest dirs for test
-	_ = os.MkdirAll(srcPath, 0x666)
-	_ = os.MkdirAll(destPath, 0x666)
+	_ = os.MkdirAll(srcPath, 0755)
+	_ = os.MkdirAll(destPath, 0755)
 
 	srcF1Name := path.Join(srcPath, "file1")
 	srcF1, _ := os.Create(srcF1Name)
@@ -61,11 +60,6 @@ func TestSchema_SyncAllEntries(t *testing.T) {
 
 	// Create all files needed for tests
 
-	defer func(dirToClean string) {
-		// Test cleanup
-		os.RemoveAll(dirToClean)
-	}(tmpDir)
-
 	tests := []struct {
 		name string // description of this test case
 		// Named input parameters for receiver constructor.
@@ -77,23 +71,23 @@ func TestSchema_SyncAllEntries(t *testing.T) {
 	}{
 		{
 			name: "non abs source is changed to abs",
-			data: &Schema{
+			data: &Schema{Entries: []SyncDefinition{
 				{
 					Source:      "file1",
 					Destination: path.Join(destPath, "dFile1"),
 				},
-			},
-			conf:    &confMock{srcPath},
+			}},
+			conf:    &confMock{path.Join(srcPath, SYNC_FILE_NAME)},
 			wantErr: false,
 			checkFunc: func() error {
 				f, err := os.Lstat(path.Join(destPath, "dFile1"))
 				if err != nil {
 					return err
 				}
-				if f.Mode()&os.ModeSymlink == 1 {
+				if f.Mode()&os.ModeSymlink == 0 {
 					return fmt.Errorf("File exist but is not a symlink (name: %s)", f.Name())
 				}
-				if resolvedLink, err := filepath.EvalSymlinks(f.Name()); err != nil {
+				if resolvedLink, err := filepath.EvalSymlinks(path.Join(destPath, f.Name())); err != nil {
 					return err
 				} else if resolvedLink != srcF1Name {
 					return fmt.Errorf("link points to wrong file (link:%s, target:%s)", f.Name(), resolvedLink)
@@ -104,12 +98,12 @@ func TestSchema_SyncAllEntries(t *testing.T) {
 		},
 		{
 			name: "abs path is not changed",
-			data: &Schema{
+			data: &Schema{Entries: []SyncDefinition{
 				{
 					Source:      srcF1Name,
 					Destination: path.Join(destPath, "dFile1abs"),
 				},
-			},
+			}},
 			conf:    &confMock{""},
 			wantErr: false,
 			checkFunc: func() error {
@@ -117,10 +111,10 @@ func TestSchema_SyncAllEntries(t *testing.T) {
 				if err != nil {
 					return err
 				}
-				if f.Mode()&os.ModeSymlink == 1 {
+				if f.Mode()&os.ModeSymlink == 0 {
 					return fmt.Errorf("File exist but is not a symlink (name: %s)", f.Name())
 				}
-				if resolvedLink, err := filepath.EvalSymlinks(f.Name()); err != nil {
+				if resolvedLink, err := filepath.EvalSymlinks(path.Join(destPath, f.Name())); err != nil {
 					return err
 				} else if resolvedLink != srcF1Name {
 					return fmt.Errorf("link points to wrong file (link:%s, target:%s)", f.Name(), resolvedLink)
@@ -0,0 +1,153 @@
+package filesync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is what templated sources are rendered with
+type TemplateData struct {
+	// Vars from sync file overridden by the ones set for this machine
+	Vars map[string]string
+	// Env is the environment ftuck runs in
+	Env map[string]string
+	// Host describes machine on which sync is run
+	Host Facts
+}
+
+func (r *Resolver) templateData() TemplateData {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	vars := r.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	return TemplateData{
+		Vars: vars,
+		Env:  env,
+		Host: r.Facts,
+	}
+}
+
+// Render executes templated source file. Missing variables are errors
+// so half rendered files never end up in destination.
+func (r *Resolver) Render(src string) ([]byte, error) {
+	d, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	return r.render(src, d)
+}
+
+func (r *Resolver) render(name string, d []byte) ([]byte, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"env": func(key string) string {
+				val, _ := r.env(key)
+				return val
+			},
+		}).
+		Parse(string(d))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = tmpl.Execute(buf, r.templateData())
+	if err != nil {
+		return nil, err
+	}
+	// never nil, so empty output is still told apart from plain copies
+	return []byte(buf.String()), nil
+}
+
+// content returns what is written to destination of encrypted or templated entry.
+// Encrypted templates are decrypted first.
+func (r *Resolver) content(sd SyncDefinition, src string) ([]byte, error) {
+	d, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	if sd.Encrypted {
+		d, err = r.decrypt(d)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting: %w", err)
+		}
+	}
+	if sd.Template {
+		d, err = r.render(src, d)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// contentStatus prepares content of encrypted or templated entry, errors block the entry.
+// Secrets are readable only by the owner, templates keep permissions of source
+// unless entry sets its own. Entry perm cannot open secret to group or others.
+func contentStatus(r *Resolver, es EntryStatus, srcFi os.FileInfo) EntryStatus {
+	d, err := r.content(es.Definition, es.Source)
+	if err == nil {
+		es.Checksum, err = r.contentChecksum(es.Definition, d)
+	}
+	if err != nil {
+		es.State = StateBlocked
+		es.Reason = err.Error()
+		es.unsupported = true
+		return es
+	}
+	es.content = d
+	es.perm = srcFi.Mode().Perm()
+	if es.Definition.Encrypted {
+		es.perm = 0600
+	}
+	if perm, ok := es.Definition.FilePerm(); ok {
+		es.perm = perm
+	}
+	if es.Definition.Encrypted && es.perm&0077 != 0 {
+		es.State = StateBlocked
+		es.Reason = fmt.Sprintf("perm %04o would make decrypted secret readable by others", es.perm)
+		es.unsupported = true
+	}
+	return es
+}
+
+// contentChecksum returns checksum of content written to destination of entry,
+// keyed with identity for encrypted entries
+func (r *Resolver) contentChecksum(sd SyncDefinition, d []byte) (string, error) {
+	if sd.Encrypted {
+		return r.secretChecksum(d)
+	}
+	return contentChecksum(d), nil
+}
+
+// destinationChecksum returns checksum of destination comparable with checksum of content
+// written there, regular files of encrypted entries get keyed checksum
+func destinationChecksum(r *Resolver, es EntryStatus, fi os.FileInfo) (string, error) {
+	if !es.Definition.Encrypted || !fi.Mode().IsRegular() {
+		return Checksum(es.Destination)
+	}
+	d, err := os.ReadFile(es.Destination)
+	if err != nil {
+		return "", err
+	}
+	return r.secretChecksum(d)
+}
+
+// contentChecksum returns the same checksum Checksum gives for file with content d
+func contentChecksum(d []byte) string {
+	h := sha256.New()
+	h.Write([]byte(".\x00"))
+	h.Write(d)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,127 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+type confVarsMock struct {
+	confStateMock
+	vars map[string]string
+}
+
+// GetVars implements varsGetter.
+func (c *confVarsMock) GetVars() map[string]string {
+	return c.vars
+}
+
+func TestResolver_Render(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := path.Join(tmpDir, "tmpl")
+	r := &Resolver{
+		Lookup: func(key string) (string, bool) {
+			return "bar", key == "FOO"
+		},
+		Facts: Facts{Hostname: "box", OS: "linux", Arch: "amd64", User: "me"},
+		Vars:  map[string]string{"email": "me@example.com"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"plain", "no vars\n", "no vars\n", false},
+		{"vars", "email = {{ .Vars.email }}", "email = me@example.com", false},
+		{"facts", "{{ .Host.User }}@{{ .Host.Hostname }} {{ .Host.OS }}/{{ .Host.Arch }}", "me@box linux/amd64", false},
+		{"env func", "{{ env \"FOO\" }}", "bar", false},
+		{"conditional", "{{ if eq .Host.OS \"darwin\" }}mac{{ else }}other{{ end }}", "other", false},
+		{"empty", "", "", false},
+		{"missing var", "{{ .Vars.missing }}", "", true},
+		{"syntax error", "{{ .Vars.email", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.WriteFile(src, []byte(tt.template), 0644)
+			got, err := r.Render(src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_PlanTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	home := path.Join(tmpDir, "home")
+	_ = os.MkdirAll(repo, 0755)
+	_ = os.MkdirAll(home, 0755)
+	dest := path.Join(home, "gitconfig")
+	_ = os.WriteFile(path.Join(repo, "gitconfig"), []byte("email = {{ .Vars.email }}\nsize = {{ .Vars.size }}\n"), 0600)
+	conf := &confVarsMock{
+		confStateMock{confMock{path.Join(repo, SYNC_FILE_NAME)}, path.Join(tmpDir, "state")},
+		map[string]string{"size": "12"},
+	}
+	s := &Schema{
+		Vars:    map[string]string{"email": "me@example.com", "size": "10"},
+		Entries: []SyncDefinition{{Source: "gitconfig", Destination: dest, Template: true}},
+	}
+
+	status := func(want EntryState, wantDrift Drift) EntryStatus {
+		t.Helper()
+		statuses, err := s.Status(conf)
+		if err != nil {
+			t.Fatalf("Status() failed: %v", err)
+		}
+		if statuses[0].State != want || statuses[0].Drift != wantDrift {
+			t.Fatalf("status = %s (%s: %s), want %s (%s)", statuses[0].State, statuses[0].Drift, statuses[0].Reason, want, wantDrift)
+		}
+		return statuses[0]
+	}
+
+	status(StateMissing, "")
+	p, err := s.Plan(conf, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if p.Actions[0].Kind != ActionCopy {
+		t.Fatalf("action = %s, want %s", p.Actions[0].Kind, ActionCopy)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	d, _ := os.ReadFile(dest)
+	if string(d) != "email = me@example.com\nsize = 12\n" {
+		t.Fatalf("rendered destination = %q", d)
+	}
+	status(StateCopied, "")
+
+	// changed variable makes rendered output stale
+	s.Vars["email"] = "work@example.com"
+	es := status(StateDrifted, DriftSource)
+	diff, err := DiffEntry(es)
+	if err != nil {
+		t.Fatalf("DiffEntry() failed: %v", err)
+	}
+	if !strings.Contains(diff, "-email = work@example.com") || !strings.Contains(diff, "+email = me@example.com") {
+		t.Errorf("DiffEntry() = %q", diff)
+	}
+
+	if _, err := s.Pull(conf, nil, false); err != nil {
+		t.Fatalf("Pull() failed: %v", err)
+	}
+	if d, _ := os.ReadFile(path.Join(repo, "gitconfig")); !strings.Contains(string(d), "{{ .Vars.email }}") {
+		t.Fatalf("template was overwritten by pull: %q", d)
+	}
+
+	s.Vars = nil
+	conf.vars = nil
+	status(StateBlocked, "")
+}
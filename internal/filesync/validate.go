@@ -0,0 +1,376 @@
+package filesync
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a single problem found in sync file
+type Issue struct {
+	Line    int
+	Column  int
+	Message string
+	// def is entry the issue was found in, nil for issues of whole sync file
+	def *SyncDefinition
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s", i.Line, i.Column, i.Message)
+}
+
+// InEntry tells if issue was found in entry or package rather than in whole sync file
+func (i Issue) InEntry() bool {
+	return i.def != nil
+}
+
+// entryIssues returns error describing issues found in given definition, nil if there are none
+func entryIssues(issues []Issue, sd SyncDefinition) error {
+	msgs := []string{}
+	for _, i := range issues {
+		if i.def != nil && reflect.DeepEqual(*i.def, sd) {
+			msgs = append(msgs, i.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidEntry, strings.Join(msgs, "; "))
+}
+
+func issueAt(n *yaml.Node, format string, args ...any) Issue {
+	return Issue{
+		Line:    n.Line,
+		Column:  n.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ValidateSchema checks sync file content and returns all issues found ordered by position.
+// Error is returned only when content is not a valid yaml document.
+func ValidateSchema(data []byte, r *Resolver) ([]Issue, error) {
+	doc := yaml.Node{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return []Issue{}, nil
+	}
+
+	root := doc.Content[0]
+	issues := []Issue{}
+	entries := root
+	if root.Kind == yaml.MappingNode {
+		// keys of entries and packages are checked with them so issues know their entry
+		issues = append(issues, checkKeys(withoutKeys(root, "entries", "packages"), reflect.TypeFor[Schema]())...)
+		entries = mappingValue(root, "entries")
+	}
+	if entries == nil || entries.Kind != yaml.SequenceNode {
+		entries = &yaml.Node{Kind: yaml.SequenceNode}
+	}
+
+	if vars := mappingValue(root, "vars"); vars != nil {
+		r = withDefaultVars(r, vars)
+	}
+
+	v := &validator{r: r, issues: issues}
+	if dirPerm := mappingValue(mappingValue(root, "settings"), "dir_perm"); dirPerm != nil {
+		v.checkPerm(dirPerm)
+	}
+	if timeout := mappingValue(mappingValue(root, "settings"), "hook_timeout"); timeout != nil {
+		if d, err := time.ParseDuration(timeout.Value); err != nil || d <= 0 {
+			v.add(timeout, "invalid hook timeout %q, use duration like 30s", timeout.Value)
+		}
+	}
+	if packages := mappingValue(root, "packages"); packages != nil && packages.Kind == yaml.SequenceNode {
+		for _, n := range packages.Content {
+			v.checkPackage(n)
+		}
+	}
+	for _, n := range entries.Content {
+		v.checkEntry(n)
+	}
+	v.checkOverlaps()
+
+	slices.SortStableFunc(v.issues, func(a, b Issue) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return v.issues, nil
+}
+
+// withDefaultVars returns copy of resolver with sync file variables
+// added under the ones resolver already has
+func withDefaultVars(r *Resolver, n *yaml.Node) *Resolver {
+	defaults := map[string]string{}
+	if n.Decode(&defaults) != nil {
+		return r
+	}
+	res := *r
+	res.Vars = maps.Clone(defaults)
+	maps.Copy(res.Vars, r.Vars)
+	return &res
+}
+
+// withoutKeys returns copy of mapping node with values of given keys left empty
+func withoutKeys(n *yaml.Node, keys ...string) *yaml.Node {
+	res := *n
+	res.Content = slices.Clone(n.Content)
+	for i := 0; i+1 < len(res.Content); i += 2 {
+		if slices.Contains(keys, res.Content[i].Value) {
+			res.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode}
+		}
+	}
+	return &res
+}
+
+// mappingValue returns value node for given key or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlFields maps yaml keys of struct to their types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	res := map[string]reflect.Type{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		res[name] = f.Type
+	}
+	return res
+}
+
+// checkKeys reports keys which do not exist in type that node is decoded into
+func checkKeys(n *yaml.Node, t reflect.Type) []Issue {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	issues := []Issue{}
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			ft, ok := fields[key.Value]
+			if !ok {
+				issues = append(issues, issueAt(key, "unknown key %q", key.Value))
+				continue
+			}
+			issues = append(issues, checkKeys(n.Content[i+1], ft)...)
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && n.Kind == yaml.SequenceNode:
+		for _, c := range n.Content {
+			issues = append(issues, checkKeys(c, t.Elem())...)
+		}
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			issues = append(issues, checkKeys(n.Content[i], t.Elem())...)
+		}
+	}
+	return issues
+}
+
+type validatedEntry struct {
+	node     *yaml.Node
+	destNode *yaml.Node
+	def      SyncDefinition
+	dest     string
+}
+
+type validator struct {
+	r       *Resolver
+	issues  []Issue
+	entries []validatedEntry
+	// def is entry being checked, issues added are attributed to it
+	def *SyncDefinition
+}
+
+func (v *validator) add(n *yaml.Node, format string, args ...any) {
+	i := issueAt(n, format, args...)
+	i.def = v.def
+	v.issues = append(v.issues, i)
+}
+
+// addKeys reports unknown keys of entry node
+func (v *validator) addKeys(n *yaml.Node, t reflect.Type) {
+	for _, i := range checkKeys(n, t) {
+		i.def = v.def
+		v.issues = append(v.issues, i)
+	}
+}
+
+func (v *validator) checkEntry(n *yaml.Node) {
+	sd := SyncDefinition{}
+	err := n.Decode(&sd)
+	if err != nil {
+		v.add(n, "invalid entry: %v", err)
+		return
+	}
+	v.def = &sd
+	defer func() { v.def = nil }()
+	v.addKeys(n, reflect.TypeFor[SyncDefinition]())
+
+	srcNode := mappingValue(n, "src")
+	if srcNode == nil {
+		srcNode = n
+	}
+	destNode := mappingValue(n, "dest")
+	if destNode == nil {
+		destNode = n
+	}
+
+	valid := true
+	if strings.TrimSpace(sd.Source) == "" {
+		v.add(srcNode, "empty source")
+		valid = false
+	}
+	if strings.TrimSpace(sd.Destination) == "" {
+		v.add(destNode, "empty destination")
+		valid = false
+	}
+	if !valid {
+		return
+	}
+
+	switch {
+	case sd.Mode != "" && !slices.Contains([]string{MODE_SYMLINK, MODE_COPY, MODE_HARDLINK}, sd.Mode):
+		v.add(mappingValue(n, "mode"), "unknown mode %q", sd.Mode)
+	case sd.Template && sd.Mode != "" && sd.Mode != MODE_COPY:
+		v.add(mappingValue(n, "mode"), "templates are always copied, mode %q is ignored", sd.Mode)
+	case sd.Encrypted && sd.Mode != "" && sd.Mode != MODE_COPY:
+		v.add(mappingValue(n, "mode"), "encrypted entries are always copied, mode %q is ignored", sd.Mode)
+	case sd.Template && sd.Mirror:
+		v.add(mappingValue(n, "template"), "mirrored entries cannot be templates")
+	case sd.Encrypted && sd.Mirror:
+		v.add(mappingValue(n, "encrypted"), "mirrored entries cannot be encrypted")
+	case sd.Perm != "" && sd.Mirror:
+		v.add(mappingValue(n, "perm"), "perm is not supported for mirrored entries")
+	case (sd.Owner != "" || sd.Group != "") && sd.Mirror:
+		v.add(n, "owner and group are not supported for mirrored entries")
+	}
+	if on := mappingValue(n, "owner"); on != nil {
+		if _, _, err := (SyncDefinition{Owner: sd.Owner}).FileOwner(); err != nil {
+			v.add(on, "unknown user %q", sd.Owner)
+		}
+	}
+	if gn := mappingValue(n, "group"); gn != nil {
+		if _, _, err := (SyncDefinition{Group: sd.Group}).FileOwner(); err != nil {
+			v.add(gn, "unknown group %q", sd.Group)
+		}
+	}
+	for _, key := range []string{"perm", "dir_perm"} {
+		if pn := mappingValue(n, key); pn != nil {
+			v.checkPerm(pn)
+		}
+	}
+	if perm, ok := sd.FilePerm(); ok && sd.Encrypted && perm&0077 != 0 {
+		v.add(mappingValue(n, "perm"), "perm %s would make decrypted secret readable by others", sd.Perm)
+	}
+
+	re := v.r.Resolve(sd)
+	// sources of entries for other machines may exist only there
+	if _, err := os.Lstat(re.Source); err != nil && re.SkipReason == "" {
+		v.add(srcNode, "source %s does not exist", re.Source)
+	}
+	if isWithin(v.r.RepoDir, re.Destination) {
+		v.add(destNode, "destination %s is inside repo", re.Destination)
+	}
+	v.addEntry(n, destNode, sd, re.Destination)
+}
+
+// addEntry reports duplicate destination and keeps entry for overlap checks
+func (v *validator) addEntry(n *yaml.Node, destNode *yaml.Node, sd SyncDefinition, dest string) {
+	for _, other := range v.entries {
+		if other.dest == dest && sameScope(other.def, sd) && !(other.def.Mirror && sd.Mirror) {
+			v.add(destNode, "duplicate destination %s (first defined at %d:%d)", dest, other.node.Line, other.node.Column)
+		}
+	}
+
+	v.entries = append(v.entries, validatedEntry{node: n, destNode: destNode, def: sd, dest: dest})
+}
+
+func (v *validator) checkPerm(n *yaml.Node) {
+	if _, err := ParsePerm(n.Value); err != nil {
+		v.add(n, "invalid permissions %q, use octal notation like 0600", n.Value)
+	}
+}
+
+func (v *validator) checkPackage(n *yaml.Node) {
+	p := Package{}
+	err := n.Decode(&p)
+	if err != nil {
+		v.add(n, "invalid package: %v", err)
+		return
+	}
+	sd := p.Definition()
+	v.def = &sd
+	defer func() { v.def = nil }()
+	v.addKeys(n, reflect.TypeFor[Package]())
+
+	nameNode := mappingValue(n, "name")
+	if nameNode == nil {
+		nameNode = n
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		v.add(nameNode, "empty package name")
+		return
+	}
+
+	targetNode := mappingValue(n, "target")
+	if targetNode == nil {
+		targetNode = nameNode
+	}
+
+	re := v.r.Resolve(sd)
+	fi, err := os.Stat(re.Source)
+	if (err != nil || !fi.IsDir()) && re.SkipReason == "" {
+		v.add(nameNode, "package %s is not a directory in repo", p.Name)
+	}
+	v.addEntry(n, targetNode, sd, re.Destination)
+}
+
+// checkOverlaps reports entries with destinations nested in other entries destinations.
+// Mirrored directories are real so other entries can put files inside them.
+func (v *validator) checkOverlaps() {
+	for _, inner := range v.entries {
+		for _, outer := range v.entries {
+			if outer.def.Mirror || inner.dest == outer.dest || !isWithin(outer.dest, inner.dest) || !sameScope(inner.def, outer.def) {
+				continue
+			}
+			v.def = &inner.def
+			v.add(inner.destNode, "destination %s overlaps with %s (defined at %d:%d)", inner.dest, outer.dest, outer.node.Line, outer.node.Column)
+		}
+	}
+	v.def = nil
+}
+
+// sameScope checks if both entries apply on the same machines and profiles
+func sameScope(a, b SyncDefinition) bool {
+	return reflect.DeepEqual(a.When, b.When) && slices.Equal(a.Profiles, b.Profiles)
+}
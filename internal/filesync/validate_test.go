@@ -0,0 +1,105 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := path.Join(tmpDir, "repo")
+	_ = os.MkdirAll(path.Join(repo, "nvim"), 0755)
+	_ = os.WriteFile(path.Join(repo, "bashrc"), []byte("bashrc"), 0644)
+	r := &Resolver{RepoDir: repo, HomeDir: "/home/user"}
+
+	tests := []struct {
+		name string // description of this test case
+		data string
+		// "line:col: message prefix" of every expected issue
+		want []string
+	}{
+		{
+			name: "valid file",
+			data: "version: 2\nentries:\n  - src: bashrc\n    dest: ~/.bashrc\n  - src: bashrc\n    dest: ~/.bashrc\n    when:\n      os: [plan9]\n",
+			want: []string{},
+		},
+		{
+			name: "unknown keys",
+			data: "version: 2\nsetings: {}\nentries:\n  - src: bashrc\n    dst: ~/.bashrc\n    when:\n      host: [a]\n",
+			want: []string{"2:1: unknown key", "4:5: empty destination", "5:5: unknown key", "7:7: unknown key"},
+		},
+		{
+			name: "empty source in bare list",
+			data: "- src: ''\n  dest: ~/.x\n",
+			want: []string{"1:8: empty source"},
+		},
+		{
+			name: "missing source and destination in repo",
+			data: "- src: nope\n  dest: " + path.Join(repo, "x") + "\n",
+			want: []string{"1:8: source", "2:9: destination"},
+		},
+		{
+			name: "duplicate destination",
+			data: "- src: bashrc\n  dest: ~/.bashrc\n- src: bashrc\n  dest: /home/user/.bashrc\n",
+			want: []string{"4:9: duplicate destination"},
+		},
+		{
+			name: "overlapping directories",
+			data: "- src: nvim\n  dest: ~/.config\n- src: bashrc\n  dest: ~/.config/nvim/init.lua\n",
+			want: []string{"4:9: destination"},
+		},
+		{
+			name: "missing source for other machine",
+			data: "- src: nope\n  dest: ~/.nope\n  when:\n    os: [plan9]\n",
+			want: []string{},
+		},
+		{
+			name: "packages",
+			data: "packages:\n  - name: nvim\n  - name: nope\n    when:\n      os: [plan9]\n  - name: nvim\n    target: ~/.config\nentries:\n  - src: bashrc\n    dest: ~/.bashrc\n  - src: bashrc\n    dest: ~/.config\n",
+			want: []string{"12:11: duplicate destination"},
+		},
+		{
+			name: "entry inside package target",
+			data: "packages:\n  - name: nvim\n    target: ~/.config/nvim\nentries:\n  - src: bashrc\n    dest: ~/.config\n",
+			want: []string{"3:13: destination"},
+		},
+		{
+			name: "invalid permissions",
+			data: "settings:\n  dir_perm: 0999\nentries:\n  - src: bashrc\n    dest: ~/.bashrc\n    perm: 0600\n  - src: bashrc\n    dest: ~/.profile\n    perm: rw\n",
+			want: []string{"2:13: invalid permissions", "9:11: invalid permissions"},
+		},
+		{
+			name: "unknown owner and group",
+			data: "- src: bashrc\n  dest: ~/.bashrc\n  owner: no-such-user\n  group: root\n- src: bashrc\n  dest: ~/.profile\n  owner: '1000'\n  group: no-such-group\n",
+			want: []string{"3:10: unknown user", "8:10: unknown group"},
+		},
+		{
+			name: "secret readable by others",
+			data: "- src: bashrc\n  dest: ~/.netrc\n  encrypted: true\n  perm: 0640\n- src: bashrc\n  dest: ~/.authinfo\n  encrypted: true\n  perm: 0400\n",
+			want: []string{"4:9: perm 0640 would make"},
+		},
+		{
+			name: "hooks",
+			data: "settings:\n  hook_timeout: soon\nhooks:\n  before: [true]\n  after_all: [true]\nentries:\n  - src: bashrc\n    dest: ~/.bashrc\n    hooks:\n      on_change: [true]\n",
+			want: []string{"2:17: invalid hook timeout", "5:3: unknown key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateSchema([]byte(tt.data), r)
+			if err != nil {
+				t.Fatalf("ValidateSchema() failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateSchema() = %v, want %v", got, tt.want)
+			}
+			for i, issue := range got {
+				if !strings.HasPrefix(issue.String(), tt.want[i]) {
+					t.Errorf("issue %d = %s, want %s...", i, issue, tt.want[i])
+				}
+			}
+		})
+	}
+}
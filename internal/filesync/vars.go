@@ -1,3 +1,127 @@
 package filesync
 
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 const SYNC_FILE_NAME string = ".ftucksync.yaml"
+
+// Origins of variables from least to most specific
+const (
+	ORIGIN_SYNC_FILE string = "syncfile"
+	ORIGIN_CONFIG    string = "config"
+	ORIGIN_VARS_FILE string = "varsfile"
+)
+
+type varsGetter interface {
+	GetVars() map[string]string
+}
+
+type varsFileGetter interface {
+	GetVarsFile() string
+}
+
+// Variable is a single template and path variable with the place it was set in
+type Variable struct {
+	Name   string `json:"name" yaml:"name"`
+	Value  string `json:"value" yaml:"value"`
+	Origin string `json:"origin" yaml:"origin"`
+	// Path of file variable was read from, empty for configuration
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// varsLayer adds variables from one origin overriding ones already set
+func varsLayer(vars []Variable, values map[string]string, origin string, path string) []Variable {
+	for name, value := range values {
+		v := Variable{Name: name, Value: value, Origin: origin, Path: path}
+		i := slices.IndexFunc(vars, func(v Variable) bool { return v.Name == name })
+		if i < 0 {
+			vars = append(vars, v)
+		} else {
+			vars[i] = v
+		}
+	}
+	return vars
+}
+
+// MachineVars returns variables set for this machine in configuration and vars file,
+// vars file wins over configuration
+func MachineVars(conf syncFileGetter) ([]Variable, error) {
+	vars := []Variable{}
+	if vg, ok := conf.(varsGetter); ok {
+		vars = varsLayer(vars, vg.GetVars(), ORIGIN_CONFIG, "")
+	}
+
+	vfg, ok := conf.(varsFileGetter)
+	if !ok || vfg.GetVarsFile() == "" {
+		return vars, nil
+	}
+	home := os.Getenv("HOME")
+	p := resolveAgainst(home, ExpandPath(vfg.GetVarsFile(), home, os.LookupEnv))
+	values, err := readVarsFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return varsLayer(vars, values, ORIGIN_VARS_FILE, p), nil
+}
+
+// readVarsFile reads yaml mapping of variable names to values.
+// Missing file is not an error so the same configuration works before the file is created.
+func readVarsFile(p string) (map[string]string, error) {
+	d, err := os.ReadFile(p)
+	if err != nil && os.IsNotExist(err) {
+		slog.Warn("vars file does not exist", "path", p)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	err = yaml.Unmarshal(d, &values)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Variables returns effective variables sorted by name. Defaults from sync file
+// are overridden by the ones set for this machine.
+func (s *Schema) Variables(conf syncFileGetter) ([]Variable, error) {
+	machine, err := MachineVars(conf)
+	if err != nil {
+		return nil, err
+	}
+	vars := varsLayer([]Variable{}, s.Vars, ORIGIN_SYNC_FILE, conf.GetSyncFilePath())
+	for _, v := range machine {
+		vars = varsLayer(vars, map[string]string{v.Name: v.Value}, v.Origin, v.Path)
+	}
+	slices.SortFunc(vars, func(a, b Variable) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return vars, nil
+}
+
+// VarsMap turns variables into name to value map
+func VarsMap(vars []Variable) map[string]string {
+	res := make(map[string]string, len(vars))
+	for _, v := range vars {
+		res[v.Name] = v.Value
+	}
+	return res
+}
+
+// newResolver creates resolver with effective variables of the schema
+func (s *Schema) newResolver(conf syncFileGetter) (*Resolver, error) {
+	vars, err := s.Variables(conf)
+	if err != nil {
+		return nil, err
+	}
+	r := NewResolver(conf)
+	r.Vars = VarsMap(vars)
+	return r, nil
+}
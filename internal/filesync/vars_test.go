@@ -0,0 +1,102 @@
+package filesync
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+type confVarsFileMock struct {
+	confVarsMock
+	varsFile string
+}
+
+// GetVarsFile implements varsFileGetter.
+func (c *confVarsFileMock) GetVarsFile() string {
+	return c.varsFile
+}
+
+func TestSchema_Variables(t *testing.T) {
+	tmpDir := t.TempDir()
+	syncFile := path.Join(tmpDir, SYNC_FILE_NAME)
+	varsFile := path.Join(tmpDir, "vars.yaml")
+	_ = os.WriteFile(varsFile, []byte("font_size: \"14\"\nhost_only: x\n"), 0644)
+	s := &Schema{Vars: map[string]string{"email": "me@example.com", "font_size": "10", "editor": "vim"}}
+
+	tests := []struct {
+		name    string
+		conf    syncFileGetter
+		want    []Variable
+		wantErr bool
+	}{
+		{
+			name: "sync file defaults",
+			conf: &confMock{syncFile},
+			want: []Variable{
+				{"editor", "vim", ORIGIN_SYNC_FILE, syncFile},
+				{"email", "me@example.com", ORIGIN_SYNC_FILE, syncFile},
+				{"font_size", "10", ORIGIN_SYNC_FILE, syncFile},
+			},
+		},
+		{
+			name: "vars file overrides configuration which overrides sync file",
+			conf: &confVarsFileMock{
+				confVarsMock{confStateMock{confMock{syncFile}, ""}, map[string]string{"font_size": "12", "editor": "nvim"}},
+				varsFile,
+			},
+			want: []Variable{
+				{"editor", "nvim", ORIGIN_CONFIG, ""},
+				{"email", "me@example.com", ORIGIN_SYNC_FILE, syncFile},
+				{"font_size", "14", ORIGIN_VARS_FILE, varsFile},
+				{"host_only", "x", ORIGIN_VARS_FILE, varsFile},
+			},
+		},
+		{
+			name: "missing vars file is ignored",
+			conf: &confVarsFileMock{confVarsMock{confStateMock{confMock{syncFile}, ""}, nil}, path.Join(tmpDir, "missing.yaml")},
+			want: []Variable{
+				{"editor", "vim", ORIGIN_SYNC_FILE, syncFile},
+				{"email", "me@example.com", ORIGIN_SYNC_FILE, syncFile},
+				{"font_size", "10", ORIGIN_SYNC_FILE, syncFile},
+			},
+		},
+		{
+			name:    "invalid vars file",
+			conf:    &confVarsFileMock{confVarsMock{confStateMock{confMock{syncFile}, ""}, nil}, syncFile},
+			wantErr: true,
+		},
+	}
+	_ = os.WriteFile(syncFile, []byte("- not a mapping\n"), 0644)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Variables(tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Variables() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_ResolveWithVars(t *testing.T) {
+	t.Setenv("FTUCK_TEST_APP", "from-env")
+	t.Setenv("FTUCK_TEST_ENV", "env")
+	r := &Resolver{
+		RepoDir: "/repo",
+		HomeDir: "/home/user",
+		Vars:    map[string]string{"FTUCK_TEST_APP": "from-vars", "config_dir": "/home/user/.config"},
+	}
+
+	got := r.Resolve(SyncDefinition{
+		Source:      "$FTUCK_TEST_APP/$FTUCK_TEST_ENV",
+		Destination: "${config_dir}/app",
+		When:        &Condition{Env: map[string]string{"FTUCK_TEST_APP": "from-env"}},
+	})
+	want := ResolvedEntry{Source: "/repo/from-vars/env", Destination: "/home/user/.config/app"}
+	if got != want {
+		t.Errorf("Resolve() = %+v, want %+v", got, want)
+	}
+}
@@ -3,22 +3,48 @@ package main
 import (
 	"context"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/mustafmst/ftuck/internal/cli"
 	"github.com/mustafmst/ftuck/internal/commands"
 )
 
 func main() {
-	ctx := context.Background()
+	// interrupted sync rolls back instead of leaving home dir half synced
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	cmd := cli.NewCommandWithSubcommands(
 		"app",
 		"aplication root",
 		commands.CreateInitCommand(ctx),
 		commands.CreateAddSyncCommand(ctx),
 		commands.CreateSyncAllCommand(ctx),
+		commands.CreateStatusCommand(ctx),
+		commands.CreateRestoreCommand(ctx),
+		commands.CreateAdoptCommand(ctx),
+		commands.CreateRemoveCommand(ctx),
+		commands.CreateListCommand(ctx),
+		commands.CreateMigrateCommand(ctx),
+		commands.CreateValidateCommand(ctx),
+		commands.CreateStowCommand(ctx),
+		commands.CreateDiffCommand(ctx),
+		commands.CreatePullCommand(ctx),
+		commands.CreateVarsCommand(ctx),
+		commands.CreateKeygenCommand(ctx),
+		commands.CreateEncryptCommand(ctx),
+		commands.CreateDecryptCommand(ctx),
+		commands.CreateEditCommand(ctx),
+		commands.CreateRecoverCommand(ctx),
+		commands.CreateHistoryCommand(ctx),
+		commands.CreateUndoCommand(ctx),
+		commands.CreatePruneCommand(ctx),
 	)
 	err := cmd.ExecuteAsRootCommand()
 	if err != nil {
 		slog.Error("root command execution", "error", err)
+		stop()
+		os.Exit(1)
 	}
 }